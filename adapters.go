@@ -0,0 +1,130 @@
+package loquet
+
+// PipeTo feeds the Chan into existing code structured
+// around a plain Go channel, easing an incremental
+// migration to loquet. It spawns a goroutine that
+// waits for the Chan to close, and then sends the
+// closeVal on dst exactly once. If closeDst is true,
+// dst is closed after the send.
+//
+// The send blocks until some goroutine receives
+// from dst (unless dst has room in its buffer), so the
+// piping goroutine lives until then. PipeTo waits
+// for the next close only; later resets and
+// closes are not piped.
+func (f *Chan[T]) PipeTo(dst chan<- *T, closeDst bool) {
+	whenClosed := f.WhenClosed()
+	spawn(func() {
+		<-whenClosed
+		val, _ := f.Read()
+		dst <- val
+		if closeDst {
+			close(dst)
+		}
+	})
+}
+
+// ValueChan returns a channel that receives the closeVal
+// once, when the Chan closes, and is then closed. In a
+// select, this delivers the value directly, sparing
+// the follow-up Read that WhenClosed requires:
+//
+//	select {
+//	case msg := <-status.ValueChan():
+//	    ... use msg ...
+//	case <-ctx.Done():
+//	}
+//
+// If the Chan is already closed, the returned channel
+// is ready with the closeVal at once. Otherwise it is
+// fed by PipeTo, and so by a goroutine that waits for
+// the next close, and lives until then. The channel
+// has a buffer of one, so the send never blocks, even
+// if nobody ever receives it. Since ValueChan makes a
+// new channel on each call, call it once per wait,
+// not repeatedly in a loop around a select.
+func (f *Chan[T]) ValueChan() <-chan *T {
+	ch := make(chan *T, 1)
+	if val, isClosed := f.Read(); isClosed {
+		ch <- val
+		close(ch)
+		return ch
+	}
+	f.PipeTo(ch, true)
+	return ch
+}
+
+// FromGoChan wraps an existing receive-only Go channel,
+// such as one returned by a third-party API, in a
+// Chan with loquet's idempotent-close semantics.
+// It is the inverse of PipeTo.
+//
+// A goroutine receives from src. The first value
+// received is broadcast with CloseWith. If src
+// is closed without delivering a value, the Chan
+// is closed with Close, leaving its closeVal nil.
+// Either way the goroutine then exits, and src is
+// not read from again.
+//
+// If src never delivers and is never closed,
+// the goroutine waits forever.
+func FromGoChan[T any](src <-chan *T) (f *Chan[T]) {
+	f = NewChan[T](nil)
+	f.CloseFrom(src)
+	return
+}
+
+// CloseFrom is FromGoChan for a Chan that already
+// exists, such as one created up front and handed
+// to a job, when the job's result will arrive on
+// a plain Go channel. It spawns a goroutine that
+// receives one value from src, and closes f with
+// it, as by CloseWith. If src is closed without
+// delivering a value, f is closed with Close,
+// keeping its current closeVal.
+//
+// CloseFrom returns at once. If f is closed by
+// other means in the meantime, the value from src
+// is ignored, as with CloseWith, but the goroutine
+// still waits on src, and exits only once src
+// delivers or is closed.
+func (f *Chan[T]) CloseFrom(src <-chan *T) {
+	spawn(func() {
+		val, ok := <-src
+		if ok {
+			f.CloseWith(val)
+			return
+		}
+		f.Close()
+	})
+}
+
+// RunAndClose runs fn, and then closes the Chan with
+// the closeVal that fn returns, as by CloseWith. It
+// codifies the usual pattern of a job that reports
+// back on a Chan when it finishes, and is neutral
+// about how the job is run: call it with go, or
+// from within a task of an errgroup.Group,
+//
+//	g.Go(func() error {
+//	    status.RunAndClose(doJob)
+//	    return nil
+//	})
+//
+// RunAndClose itself blocks until fn returns.
+// If fn panics, the Chan is still closed (with
+// Close, keeping its current closeVal) so that
+// waiters are not stranded, and the panic then
+// continues. If the Chan is already closed when fn
+// returns, fn's closeVal is ignored, as with CloseWith.
+func (f *Chan[T]) RunAndClose(fn func() *T) {
+	returned := false
+	defer func() {
+		if !returned {
+			f.Close()
+		}
+	}()
+	val := fn()
+	returned = true
+	f.CloseWith(val)
+}
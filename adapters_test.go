@@ -0,0 +1,117 @@
+package loquet_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestPipeTo(t *testing.T) {
+	msg := &Message{}
+	c := loquet.NewChan[Message](nil)
+	dst := make(chan *Message)
+	c.PipeTo(dst, true)
+
+	c.CloseWith(msg)
+	if got := <-dst; got != msg {
+		t.Fatalf("expected the closeVal on dst")
+	}
+	if _, ok := <-dst; ok {
+		t.Fatalf("expected dst to be closed after the send")
+	}
+}
+
+func TestFromGoChan(t *testing.T) {
+	msg := &Message{}
+	src := make(chan *Message, 1)
+	src <- msg
+	c := loquet.FromGoChan(src)
+	<-c.WhenClosed()
+	if val, _ := c.Read(); val != msg {
+		t.Fatalf("expected the first value from src as closeVal")
+	}
+
+	empty := make(chan *Message)
+	close(empty)
+	c = loquet.FromGoChan(empty)
+	<-c.WhenClosed()
+	if val, _ := c.Read(); val != nil {
+		t.Fatalf("expected a nil closeVal when src closed empty")
+	}
+}
+
+func TestCloseFrom(t *testing.T) {
+	msg := &Message{}
+	initial := &Message{}
+	c := loquet.NewChan[Message](initial)
+	src := make(chan *Message)
+	c.CloseFrom(src)
+	if _, isClosed := c.Read(); isClosed {
+		t.Fatalf("expected c open until src delivers")
+	}
+	src <- msg
+	waitClosed(t, c)
+	if val, _ := c.Read(); val != msg {
+		t.Fatalf("expected the value from src as closeVal")
+	}
+
+	// src closed empty: Close, keeping the closeVal.
+	c = loquet.NewChan[Message](initial)
+	empty := make(chan *Message)
+	c.CloseFrom(empty)
+	close(empty)
+	waitClosed(t, c)
+	if val, _ := c.Read(); val != initial {
+		t.Fatalf("expected the closeVal kept when src closed empty")
+	}
+}
+
+func TestRunAndClose(t *testing.T) {
+	c := loquet.NewChan[Message](nil)
+	go c.RunAndClose(func() *Message {
+		return &Message{Err: errTimeout}
+	})
+	waitClosed(t, c)
+	if val, _ := c.Read(); val.Err != errTimeout {
+		t.Fatalf("expected the closeVal returned by fn, got %v", val)
+	}
+
+	// a panicking fn still closes the Chan.
+	p := loquet.NewChan[Message](nil)
+	func() {
+		defer func() { recover() }()
+		p.RunAndClose(func() *Message { panic("job failed") })
+	}()
+	if _, isClosed := p.Read(); !isClosed {
+		t.Fatalf("expected the Chan to be closed after fn panicked")
+	}
+}
+
+func TestValueChan(t *testing.T) {
+	one := 1
+	c := loquet.NewChan[int](nil)
+	ch := c.ValueChan()
+	go c.CloseWith(&one)
+	select {
+	case v := <-ch:
+		if v != &one {
+			t.Fatalf("expected the closeVal")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected a value on close")
+	}
+	if _, ok := <-ch; ok {
+		t.Fatalf("expected the channel to be closed after the value")
+	}
+
+	// already closed: ready at once.
+	select {
+	case v := <-c.ValueChan():
+		if v != &one {
+			t.Fatalf("expected the closeVal")
+		}
+	default:
+		t.Fatalf("expected ValueChan of a closed Chan to be ready")
+	}
+}
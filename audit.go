@@ -0,0 +1,94 @@
+package loquet
+
+import (
+	"strings"
+	"time"
+)
+
+// AuditEntry describes one mutating operation
+// on a Chan, as reported to the WithAuditLog callback.
+type AuditEntry[T any] struct {
+
+	// Op is the name of the Chan method that
+	// mutated the Chan, e.g. "Set" or "CloseWith".
+	Op string
+
+	// Time is when the operation took effect.
+	Time time.Time
+
+	// Actor is the name of the Chan, as given by WithName.
+	Actor string
+
+	// Old and New are the closeVal from before
+	// and after the operation.
+	Old *T
+	New *T
+
+	// Version is the version of the Chan
+	// after the operation.
+	Version int64
+}
+
+// WithAuditLog arranges for fn to be called with an
+// AuditEntry for every mutating operation on the Chan:
+// every Set, close and reset, not just the close.
+// Operations that turn out to be no-ops, such as
+// a Close on an already closed Chan, are not reported.
+//
+// Entries are delivered in the order the operations
+// took effect. To avoid holding up other users
+// of the Chan, fn is called after the Chan's
+// internal mutex has been released, by the goroutine
+// that performed the operation (or by one that was
+// already delivering earlier entries). It is thus
+// safe for fn to call methods on the Chan, although a
+// slow fn will still slow down that goroutine.
+// An fn that must persist entries durably
+// should generally hand them off to a queue.
+func WithAuditLog[T any](fn func(AuditEntry[T])) Option[T] {
+	return func(f *Chan[T]) {
+		f.auditLog = fn
+	}
+}
+
+// WithLogger arranges for logger to be called on every
+// mutating operation on the Chan (each Set, close and
+// reset), with a short description of the event and
+// the version after it, giving a trace of the Chan's
+// lifecycle without log statements at every call site.
+// The event reads like
+//
+//	job-42: CloseWith (Open -> Closed)
+//
+// giving the Chan's name (if it has one, see WithName),
+// the operation, and the Lifecycle transition, if
+// the operation made one. Like WithAuditLog, of which
+// it is a lighter cousin, logger is called in order,
+// after the Chan's mutex has been released. With no
+// logger, the default, nothing is formatted at all.
+func WithLogger[T any](logger func(event string, version int64)) Option[T] {
+	return func(f *Chan[T]) {
+		f.logger = logger
+	}
+}
+
+// describe formats ev for the WithLogger logger.
+func (f *Chan[T]) describe(ev event[T]) string {
+	var b strings.Builder
+	if f.name != "" {
+		b.WriteString(f.name)
+		b.WriteString(": ")
+	}
+	b.WriteString(ev.op)
+	if len(ev.path) > 0 {
+		b.WriteString(" (")
+		for i, s := range ev.path {
+			if i > 0 {
+				b.WriteString(" -> ")
+			}
+			b.WriteString(s.String())
+		}
+		b.WriteString(")")
+	}
+	return b.String()
+}
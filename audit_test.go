@@ -0,0 +1,94 @@
+package loquet_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestAuditLog(t *testing.T) {
+	var entries []loquet.AuditEntry[int]
+	one, two, three := 1, 2, 3
+
+	c := loquet.NewChan(&one,
+		loquet.WithName[int]("auditee"),
+		loquet.WithAuditLog(func(e loquet.AuditEntry[int]) {
+			entries = append(entries, e)
+		}))
+
+	c.Set(&two)
+	c.CloseWith(&three)
+	c.Close() // no-op, not audited.
+	c.ReadAndReset(nil)
+
+	want := []struct {
+		op       string
+		old, new *int
+		version  int64
+	}{
+		{"Set", &one, &two, 1},
+		{"CloseWith", &two, &three, 2},
+		{"ReadAndReset", &three, nil, 3},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %v audit entries, got %v: %#v", len(want), len(entries), entries)
+	}
+	for i, w := range want {
+		e := entries[i]
+		if e.Op != w.op || e.Old != w.old || e.New != w.new || e.Version != w.version {
+			t.Fatalf("entry %v: expected %+v, got %+v", i, w, e)
+		}
+		if e.Actor != "auditee" {
+			t.Fatalf("entry %v: expected Actor 'auditee', got '%v'", i, e.Actor)
+		}
+		if e.Time.IsZero() {
+			t.Fatalf("entry %v: expected a Time", i)
+		}
+	}
+}
+
+func TestAuditLogMayCallChan(t *testing.T) {
+	var ops []string
+	var c *loquet.Chan[int]
+	c = loquet.NewChan[int](nil,
+		loquet.WithAuditLog(func(e loquet.AuditEntry[int]) {
+			ops = append(ops, e.Op)
+			if e.Op == "Set" {
+				// must not deadlock; delivered after this entry.
+				c.Close()
+			}
+		}))
+	c.Set(nil)
+	if len(ops) != 2 || ops[0] != "Set" || ops[1] != "Close" {
+		t.Fatalf("expected [Set Close], got %v", ops)
+	}
+}
+
+func TestWithLogger(t *testing.T) {
+	var events []string
+	var versions []int64
+	c := loquet.NewChan[int](nil,
+		loquet.WithName[int]("job-42"),
+		loquet.WithLogger[int](func(event string, version int64) {
+			events = append(events, event)
+			versions = append(versions, version)
+		}))
+	one := 1
+	c.Set(&one)
+	c.CloseWith(&one)
+	c.Close() // no-op: not logged.
+	c.Reset(nil)
+
+	want := []string{
+		"job-42: Set",
+		"job-42: CloseWith (Open -> Closed)",
+		"job-42: Reset (Closed -> ResetPending -> Open)",
+	}
+	if !slices.Equal(events, want) {
+		t.Fatalf("expected %q, got %q", want, events)
+	}
+	if !slices.Equal(versions, []int64{1, 2, 3}) {
+		t.Fatalf("unexpected versions %v", versions)
+	}
+}
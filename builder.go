@@ -0,0 +1,76 @@
+package loquet
+
+import (
+	"time"
+)
+
+// Builder accumulates the configuration of a
+// Chan; see Build.
+type Builder[T any] struct {
+	closeVal *T
+	opts     []Option[T]
+
+	deadline    time.Time
+	deadlineVal *T
+}
+
+// Build starts building a richly configured Chan with
+// chainable methods, as an alternative to a long list
+// of options passed to NewChan, and one that does
+// not need the type parameter repeated on each option:
+//
+//	c := loquet.Build[Message]().
+//	    WithCloseVal(&Message{}).
+//	    WithHistory(10).
+//	    WithOnClose(func(m *Message) { log.Printf("done: %v", m) }).
+//	    WithDeadline(time.Now().Add(time.Minute), &Message{Err: errTimeout}).
+//	    Build()
+//
+// Any other Option can be added with With.
+func Build[T any]() *Builder[T] {
+	return &Builder[T]{}
+}
+
+// WithCloseVal sets the initial closeVal, as
+// given to NewChan.
+func (b *Builder[T]) WithCloseVal(closeVal *T) *Builder[T] {
+	b.closeVal = closeVal
+	return b
+}
+
+// WithHistory adds the WithHistory(n) option.
+func (b *Builder[T]) WithHistory(n int) *Builder[T] {
+	return b.With(WithHistory[T](n))
+}
+
+// WithOnClose adds the WithOnClose(fn) option.
+func (b *Builder[T]) WithOnClose(fn func(closeVal *T)) *Builder[T] {
+	return b.With(WithOnClose(fn))
+}
+
+// WithDeadline makes the built Chan close with closeVal
+// at time t, as if by CloseAt, unless it closes first.
+// The timer cannot be cancelled other than by closing
+// the Chan; use CloseAt directly when that is needed.
+func (b *Builder[T]) WithDeadline(t time.Time, closeVal *T) *Builder[T] {
+	b.deadline = t
+	b.deadlineVal = closeVal
+	return b
+}
+
+// With adds any other options, in order.
+func (b *Builder[T]) With(opts ...Option[T]) *Builder[T] {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build creates the configured Chan. If a deadline
+// was given, its timer starts now. The Builder may
+// be used again to create further Chans alike.
+func (b *Builder[T]) Build() (f *Chan[T]) {
+	f = NewChan(b.closeVal, b.opts...)
+	if !b.deadline.IsZero() {
+		f.CloseAt(b.deadline, b.deadlineVal)
+	}
+	return
+}
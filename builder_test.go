@@ -0,0 +1,38 @@
+package loquet_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestBuilder(t *testing.T) {
+	zero, timedOut := 0, -1
+	closed := make(chan int, 1)
+	c := loquet.Build[int]().
+		WithCloseVal(&zero).
+		WithHistory(2).
+		WithOnClose(func(v *int) { closed <- *v }).
+		WithDeadline(time.Now().Add(10*time.Millisecond), &timedOut).
+		With(loquet.WithName[int]("built")).
+		Build()
+
+	if c.Name() != "built" {
+		t.Fatalf("expected With to apply the name option")
+	}
+	if val, _ := c.Read(); val != &zero {
+		t.Fatalf("expected the initial closeVal")
+	}
+	select {
+	case v := <-closed:
+		if v != -1 {
+			t.Fatalf("expected OnClose to see the deadline value, got %v", v)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the deadline to close the Chan")
+	}
+	if h := c.History(); len(h) != 1 || *h[0] != -1 {
+		t.Fatalf("expected the history to hold the deadline value, got %v", h)
+	}
+}
@@ -0,0 +1,124 @@
+package loquet
+
+// NewChans returns a slice of n freshly constructed,
+// independent Chans, as for a pool of n workers that
+// each report on their own Chan. Each is made by
+// NewChan(closeVal, opts...).
+//
+// Note that every Chan starts out sharing the same
+// closeVal pointer. That is fine if the initial
+// value is treated as read-only, or replaced
+// wholesale by Set and CloseWith, but mutating
+// *closeVal in place would be seen through
+// all n Chans. Pass a nil closeVal to avoid
+// the sharing altogether.
+func NewChans[T any](n int, closeVal *T, opts ...Option[T]) []*Chan[T] {
+	chans := make([]*Chan[T], n)
+	for i := range chans {
+		chans[i] = NewChan(closeVal, opts...)
+	}
+	return chans
+}
+
+// CloseAll closes every Chan in chans with closeVal,
+// as by CloseWith, for instance to shut down a pool
+// of workers. Chans that are already closed are left
+// as they are, keeping their own closeVal. Each Chan
+// is closed separately, so CloseAll is safe to call
+// concurrently with other operations on the Chans,
+// but other goroutines may see some of them
+// closed before the rest.
+func CloseAll[T any](chans []*Chan[T], closeVal *T) {
+	for _, f := range chans {
+		f.CloseWith(closeVal) // ErrAlreadyClosed is fine.
+	}
+}
+
+// SetAll calls Set(v) on every Chan in chans, to
+// broadcast an update to all of them. As with
+// CloseAll, each Chan is updated separately,
+// not all at one instant.
+func SetAll[T any](chans []*Chan[T], v *T) {
+	for _, f := range chans {
+		f.Set(v)
+	}
+}
+
+// ReadResult is the state of one Chan as
+// reported by ReadAll.
+type ReadResult[T any] struct {
+	CloseVal *T
+	IsClosed bool
+	Version  int64
+}
+
+// ReadAll reads every Chan in chans in a single pass,
+// returning their states in the same order. It is a
+// convenience for dashboards and other bulk pollers,
+// and avoids the per-call overhead of Read by
+// filling in a single preallocated slice, taking each
+// Chan's mutex just long enough to copy its state.
+//
+// Each result is consistent for its own Chan, but
+// the Chans are read one after another, not at a
+// single instant; see SnapshotAll for that.
+func ReadAll[T any](chans []*Chan[T]) []ReadResult[T] {
+	res := make([]ReadResult[T], len(chans))
+	for i, f := range chans {
+		r := &res[i]
+		f.mut.RLock()
+		r.CloseVal = f.closeVal
+		r.IsClosed = f.isClosed
+		r.Version = f.version
+		f.mut.RUnlock()
+	}
+	return res
+}
+
+// Snapshot is the state of one Chan as
+// captured by SnapshotAll.
+type Snapshot[T any] ReadResult[T]
+
+// snapshotRetries bounds the attempts SnapshotAll
+// makes to obtain a consistent view.
+const snapshotRetries = 100
+
+// SnapshotAll makes a best-effort attempt to read every
+// Chan in chans at the "same logical time", for a
+// consistent multi-Chan view on debugging dashboards.
+//
+// It reads all of the Chans twice in a row. If no Chan
+// changed between its two reads, then there was an
+// instant, between the end of the first pass and the
+// start of the second, at which every Chan held the
+// state captured, and that state is returned.
+// Otherwise SnapshotAll retries, a bounded number of
+// times, after which it returns the latest pass even
+// though it may be inconsistent.
+//
+// So this is not a true global snapshot: under a
+// steady storm of concurrent mutations it may give up,
+// and even when it succeeds, the instant it describes
+// has already passed by the time it returns.
+func SnapshotAll[T any](chans []*Chan[T]) []Snapshot[T] {
+	prev := ReadAll(chans)
+	for try := 0; try < snapshotRetries; try++ {
+		cur := ReadAll(chans)
+		same := true
+		for i := range cur {
+			if cur[i] != prev[i] {
+				same = false
+				break
+			}
+		}
+		prev = cur
+		if same {
+			break
+		}
+	}
+	snap := make([]Snapshot[T], len(prev))
+	for i, r := range prev {
+		snap[i] = Snapshot[T](r)
+	}
+	return snap
+}
@@ -0,0 +1,105 @@
+package loquet_test
+
+import (
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestReadAll(t *testing.T) {
+	one, two := 1, 2
+	open := loquet.NewChan(&one)
+	closed := loquet.NewChan[int](nil)
+	closed.CloseWith(&two)
+	setTwice := loquet.NewChan[int](nil)
+	setTwice.Set(&one)
+	setTwice.Set(&two)
+
+	res := loquet.ReadAll([]*loquet.Chan[int]{open, closed, setTwice})
+	want := []loquet.ReadResult[int]{
+		{CloseVal: &one, IsClosed: false, Version: 0},
+		{CloseVal: &two, IsClosed: true, Version: 1},
+		{CloseVal: &two, IsClosed: false, Version: 2},
+	}
+	if len(res) != len(want) {
+		t.Fatalf("expected %v results, got %v", len(want), len(res))
+	}
+	for i := range want {
+		if res[i] != want[i] {
+			t.Fatalf("result %v: expected %+v, got %+v", i, want[i], res[i])
+		}
+	}
+}
+
+func TestSnapshotAllConsistentUnderSets(t *testing.T) {
+	zero := 0
+	a := loquet.NewChan(&zero)
+	b := loquet.NewChan(&zero)
+	chans := []*loquet.Chan[int]{a, b}
+
+	// the writer always sets a before b, so at any
+	// instant a is either equal to b, or one ahead.
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			v := i
+			a.Set(&v)
+			b.Set(&v)
+		}
+	}()
+
+	consistent := 0
+	for k := 0; k < 1000; k++ {
+		snap := loquet.SnapshotAll(chans)
+		diff := *snap[0].CloseVal - *snap[1].CloseVal
+		if diff == 0 || diff == 1 {
+			consistent++
+		}
+	}
+	close(stop)
+	<-done
+
+	// retries are bounded, so allow a rare give-up.
+	if consistent < 900 {
+		t.Fatalf("expected nearly all snapshots to be consistent, got %v of 1000", consistent)
+	}
+}
+
+func TestNewChans(t *testing.T) {
+	chans := loquet.NewChans[int](3, nil)
+	if len(chans) != 3 {
+		t.Fatalf("expected 3 Chans, got %v", len(chans))
+	}
+	chans[1].Close()
+	for i, r := range loquet.ReadAll(chans) {
+		if r.IsClosed != (i == 1) {
+			t.Fatalf("Chans should be independent, but %v has isClosed=%v", i, r.IsClosed)
+		}
+	}
+}
+
+func TestCloseAllAndSetAll(t *testing.T) {
+	one, two, three := 1, 2, 3
+	chans := loquet.NewChans[int](3, nil)
+	chans[1].CloseWith(&one)
+
+	loquet.SetAll(chans, &two)
+	loquet.CloseAll(chans, &three)
+
+	// chans[1] was already closed, and keeps the value
+	// from SetAll; the others close with 3.
+	want := []int{3, 2, 3}
+	for i, r := range loquet.ReadAll(chans) {
+		if !r.IsClosed || *r.CloseVal != want[i] {
+			t.Fatalf("chan %v: expected closed with %v, got %v, %v",
+				i, want[i], *r.CloseVal, r.IsClosed)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package loquet
+
+import (
+	"sync"
+)
+
+// WhenClosedFunc spawns a goroutine that waits for
+// the Chan to close, and then calls fn with the final
+// closeVal. This saves writing the usual select-in-a-
+// goroutine boilerplate, and reduces the risk of
+// leaking such goroutines.
+//
+// The returned stop func unblocks and terminates the
+// goroutine, if fn has not yet been called, in which
+// case fn will never be called. It is safe to call
+// stop more than once. Calling stop does not wait
+// for an fn that is already running.
+//
+// WhenClosedFunc waits for the next close only.
+func (f *Chan[T]) WhenClosedFunc(fn func(closeVal *T)) (stop func()) {
+	done := make(chan struct{})
+	var once sync.Once
+	stop = func() {
+		once.Do(func() { close(done) })
+	}
+	whenClosed := f.WhenClosed()
+	f.mut.Lock()
+	f.callbacks++
+	f.mut.Unlock()
+	spawn(func() {
+		defer func() {
+			f.mut.Lock()
+			f.callbacks--
+			f.mut.Unlock()
+		}()
+		select {
+		case <-whenClosed:
+		case <-done:
+			return
+		}
+		val, _ := f.Read()
+		protect(func() { fn(val) })
+	})
+	return
+}
+
+// WaiterCount returns the number of registrations
+// waiting on the Chan: the live subscribers, from both
+// Subscribe and SubscribeBuffered; the WhenMatches
+// registrations not yet fired; the WithOnClose funcs;
+// and the callbacks still pending, such as those from
+// WhenClosedFunc that have neither run nor been stopped.
+// It is purely diagnostic, but invaluable when chasing
+// down why a close is slow (a SubscribeBuffered
+// subscriber under OverflowBlock can stall the setters)
+// or why goroutines accumulate. Goroutines blocked in
+// the waiting methods are not included; see HasWaiters.
+//
+// A pending callback is counted until its goroutine
+// exits, so the count may briefly lag behind a
+// stop, or include a callback that is running.
+func (f *Chan[T]) WaiterCount() int {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	return len(f.subs) + len(f.bsubs) + len(f.matchers) +
+		len(f.onClose) + f.callbacks
+}
+
+// HasWaiters reports whether any goroutine is
+// currently waiting on the Chan in one of its blocking
+// methods: ReadContext, ReadBlockingIfOpen,
+// ReadDeadline, WaitUntil, WaitForVariant, WaitVersions,
+// WaitWithEscalation, WaitPoll, or WaitSpin, or in an
+// Updates iteration, between values. A producer
+// can use it for backpressure, for instance to skip
+// computing an expensive closeVal while nobody is
+// listening yet.
+//
+// A waiter is counted for the whole of its call,
+// including the first check of the Chan, so it may be
+// counted just before it returns without blocking.
+// Receives on the channel from WhenClosed cannot be
+// seen by the Chan, and so are not counted: wait with
+// ReadContext instead where HasWaiters should see it.
+// Subscribers, matchers and callbacks are counted
+// separately, by WaiterCount. Like any snapshot of concurrent
+// state, the answer may be stale by the time
+// the caller acts on it.
+func (f *Chan[T]) HasWaiters() bool {
+	return f.waiting.Load() > 0
+}
+
+// OnFirstRead arranges for fn to be called exactly once,
+// the first time any reader observes the Chan with
+// Read (or ReadOrDefault, ReadSince, or one of the
+// waiting methods such as WaitUntil). This supports
+// lazy initialization, where producing the initial
+// closeVal is expensive and should be deferred until
+// someone actually asks for it: fn may simply Set
+// the closeVal.
+//
+// fn is called outside the Chan's mutex, so it may
+// call methods on the Chan, by the goroutine of the
+// first reader, before that reader takes its snapshot
+// of the Chan. The first reader therefore sees
+// whatever fn established. Other readers racing with
+// the first may see the state from before fn finished.
+//
+// If the Chan has already been read when OnFirstRead
+// is called, fn is called immediately, by the caller.
+// Several funcs may be registered; they run in
+// registration order.
+func (f *Chan[T]) OnFirstRead(fn func()) {
+	f.mut.Lock()
+	if f.everRead.Load() {
+		f.mut.Unlock()
+		protect(fn)
+		return
+	}
+	f.onFirstRead = append(f.onFirstRead, fn)
+	f.mut.Unlock()
+}
+
+// rlockForRead acquires f.mut for reading on behalf
+// of a reader. The first time the Chan is read, any
+// OnFirstRead funcs are run first, by firstRead;
+// thereafter only an atomic load is added.
+func (f *Chan[T]) rlockForRead() {
+	if !f.everRead.Load() {
+		f.firstRead()
+	}
+	f.mut.RLock()
+}
+
+// firstRead marks the Chan as read, and runs the
+// OnFirstRead funcs, with f.mut released. Readers
+// racing with the first do not wait for them.
+func (f *Chan[T]) firstRead() {
+	f.mut.Lock()
+	if f.everRead.Load() {
+		f.mut.Unlock()
+		return
+	}
+	f.everRead.Store(true)
+	due := f.onFirstRead
+	f.onFirstRead = nil
+	f.mut.Unlock()
+	for _, fn := range due {
+		protect(fn)
+	}
+}
+
+// runlockForRead releases the read lock taken by
+// rlockForRead. If the reader found the Chan closed,
+// the close is first marked observed; see observeLocked.
+// This needs only the read lock, as observed is atomic.
+func (f *Chan[T]) runlockForRead() {
+	f.observeLocked()
+	f.mut.RUnlock()
+}
+
+// WithOnClose registers fn to be called with the closeVal
+// each time the Chan closes, including closes after a
+// reset. Unlike WhenClosedFunc, no goroutine is
+// spent waiting: fn is called by the goroutine that
+// closed the Chan, after the Chan's mutex has been
+// released, so fn may call methods on the Chan. The
+// closer is held up until fn returns, so a slow fn
+// should hand its work off elsewhere. A MarkClosed
+// does not call fn; the Close that completes its
+// broadcast does.
+//
+// The option may be given more than once; the
+// funcs are then called in the order given.
+func WithOnClose[T any](fn func(closeVal *T)) Option[T] {
+	return func(f *Chan[T]) {
+		f.onClose = append(f.onClose, fn)
+	}
+}
@@ -0,0 +1,152 @@
+package loquet_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestWhenClosedFunc(t *testing.T) {
+	msg := &Message{}
+	c := loquet.NewChan[Message](nil)
+	got := make(chan *Message, 1)
+	c.WhenClosedFunc(func(val *Message) {
+		got <- val
+	})
+	c.CloseWith(msg)
+	select {
+	case val := <-got:
+		if val != msg {
+			t.Fatalf("expected fn to get the closeVal")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("fn was never called")
+	}
+}
+
+func TestWhenClosedFuncStop(t *testing.T) {
+	c := loquet.NewChan[Message](nil)
+	called := make(chan struct{}, 1)
+	stop := c.WhenClosedFunc(func(*Message) {
+		called <- struct{}{}
+	})
+	stop()
+	stop()
+	time.Sleep(10 * time.Millisecond) // let the goroutine exit.
+	c.Close()
+	select {
+	case <-called:
+		t.Fatalf("fn should not be called after stop")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestWaiterCount(t *testing.T) {
+	c := loquet.NewChan[Message](nil)
+	if n := c.WaiterCount(); n != 0 {
+		t.Fatalf("expected 0 waiters, got %v", n)
+	}
+	_, cancel := c.Subscribe()
+	stop := c.WhenClosedFunc(func(*Message) {})
+	if n := c.WaiterCount(); n != 2 {
+		t.Fatalf("expected 2 waiters, got %v", n)
+	}
+	cancel()
+	stop()
+	eventually(t, func() bool { return c.WaiterCount() == 0 })
+}
+
+func TestWaiterCountIncludesBufferedAndMatchers(t *testing.T) {
+	c := loquet.NewChan[Message](nil, loquet.WithOnClose(func(*Message) {}))
+	_, cancel := c.SubscribeBuffered(1)
+	c.WhenMatches(func(m *Message) bool { return m != nil })
+	if n := c.WaiterCount(); n != 3 {
+		t.Fatalf("expected 3 waiters, got %v", n)
+	}
+	cancel()
+	c.Set(&Message{}) // fires the matcher.
+	if n := c.WaiterCount(); n != 1 {
+		t.Fatalf("expected only the OnClose func left, got %v", n)
+	}
+}
+
+func TestOnFirstRead(t *testing.T) {
+	c := loquet.NewChan[Message](nil)
+	calls := 0
+	lazy := &Message{}
+	c.OnFirstRead(func() {
+		calls++
+		c.Set(lazy) // must not deadlock.
+	})
+	if calls != 0 {
+		t.Fatalf("fn should wait for the first read")
+	}
+	if val, _ := c.Read(); val != lazy {
+		t.Fatalf("first reader should see the lazily produced closeVal")
+	}
+	c.Read()
+	if calls != 1 {
+		t.Fatalf("expected exactly one call, got %v", calls)
+	}
+
+	late := false
+	c.OnFirstRead(func() { late = true })
+	if !late {
+		t.Fatalf("fn registered after the first read should run immediately")
+	}
+}
+
+func TestWithOnClose(t *testing.T) {
+	var got []int
+	c := loquet.NewChan[int](nil,
+		loquet.WithOnClose(func(v *int) { got = append(got, *v) }),
+		loquet.WithOnClose(func(v *int) { got = append(got, -*v) }),
+	)
+	one, two := 1, 2
+	c.CloseWith(&one)
+	c.Close() // already closed: no callback.
+	c.ReadAndReset(nil)
+	c.CloseWith(&two)
+	if len(got) != 4 || got[0] != 1 || got[1] != -1 || got[2] != 2 || got[3] != -2 {
+		t.Fatalf("expected callbacks in order on each close, got %v", got)
+	}
+}
+
+func TestWithOnCloseMarkClosed(t *testing.T) {
+	var n int
+	c := loquet.NewChan[int](nil, loquet.WithOnClose(func(*int) { n++ }))
+	c.MarkClosed()
+	if n != 0 {
+		t.Fatalf("expected MarkClosed not to call fn, got %v calls", n)
+	}
+	c.Close()
+	if n != 1 {
+		t.Fatalf("expected fn to run exactly once, got %v calls", n)
+	}
+}
+
+func TestHasWaiters(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	if c.HasWaiters() {
+		t.Fatalf("expected no waiters on a new Chan")
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.ReadContext(context.Background())
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !c.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the ReadContext caller to be counted")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Close()
+	<-done
+	if c.HasWaiters() {
+		t.Fatalf("expected no waiters once ReadContext returned")
+	}
+}
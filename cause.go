@@ -0,0 +1,96 @@
+package loquet
+
+import (
+	"context"
+	"fmt"
+)
+
+// CloseWithCause is like CloseWith, but also records
+// cause as the reason for the close, which is then
+// available from CauseChain. The returned error is
+// ErrAlreadyClosed if the Chan was already closed,
+// in which case cause is not recorded.
+func (f *Chan[T]) CloseWithCause(closeVal *T, cause error) error {
+	return f.closeWithCauses("CloseWithCause", closeVal, []error{cause})
+}
+
+// closeWithCauses closes the Chan with closeVal,
+// recording the cause chain causes.
+func (f *Chan[T]) closeWithCauses(op string, closeVal *T, causes []error) error {
+	f.mut.Lock()
+	defer f.unlock()
+
+	if f.frozen {
+		return ErrFrozen
+	}
+	if f.isClosed {
+		return ErrAlreadyClosed
+	}
+	old := f.closeVal
+	f.closeVal = closeVal
+	f.causes = causes
+	f.version++
+	f.closeLocked()
+	f.recordLocked(kindClose, op, old)
+	return nil
+}
+
+// CauseChain returns the chain of reasons why the Chan
+// closed, leaf first. This aids debugging deep
+// cancellation trees: when a Chan closes because an
+// ancestor in a tree of Chans (built with Child)
+// closed, the chain holds one entry per propagation
+// hop, each wrapping ErrAncestorClosed, followed by
+// the reason the root closed.
+//
+// The root reason is the cause supplied to
+// CloseWithCause, or context.DeadlineExceeded for
+// a close made by CloseAt. Plain Close and CloseWith
+// record no reason, so their chain is empty.
+// An open Chan also has an empty chain; a reset
+// clears the chain.
+//
+// The returned slice is a copy.
+func (f *Chan[T]) CauseChain() []error {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	return append([]error(nil), f.causes...)
+}
+
+// Child returns a new Chan that closes when f closes,
+// with f's closeVal, and with a cause chain consisting
+// of one hop wrapping ErrAncestorClosed, followed by
+// f's own cause chain. Chaining Child calls builds
+// a tree of Chans in which a close anywhere
+// propagates down to every descendant.
+//
+// The child may also be closed independently of f,
+// in which case the propagation goroutine exits.
+// The child is not affected by later resets of f.
+func (f *Chan[T]) Child() (child *Chan[T]) {
+	child = NewChan[T](nil)
+	parentClosed := f.WhenClosed()
+	childClosed := child.WhenClosed()
+	f.deriveAdd()
+	spawn(func() {
+		defer f.deriveDone()
+		select {
+		case <-parentClosed:
+		case <-childClosed:
+			return
+		}
+		f.mut.Lock()
+		val := f.closeVal
+		hop := ErrAncestorClosed
+		if f.name != "" {
+			hop = fmt.Errorf("%w (%v)", ErrAncestorClosed, f.name)
+		}
+		causes := append([]error{hop}, f.causes...)
+		f.mut.Unlock()
+		child.closeWithCauses("Child", val, causes)
+	})
+	return
+}
+
+// deadlineCauses is the cause chain for a close made by CloseAt.
+var deadlineCauses = []error{context.DeadlineExceeded}
@@ -0,0 +1,59 @@
+package loquet_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestCauseChainThreeLevels(t *testing.T) {
+	errShutdown := fmt.Errorf("shutdown requested")
+	root := loquet.NewChan[error](nil, loquet.WithName[error]("root"))
+	mid := root.Child()
+	leaf := mid.Child()
+
+	root.CloseWithCause(&errShutdown, errShutdown)
+
+	select {
+	case <-leaf.WhenClosed():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("close never propagated to the leaf")
+	}
+	chain := leaf.CauseChain()
+	if len(chain) != 3 {
+		t.Fatalf("expected 3 causes, got %v", chain)
+	}
+	if !errors.Is(chain[0], loquet.ErrAncestorClosed) ||
+		!errors.Is(chain[1], loquet.ErrAncestorClosed) ||
+		chain[2] != errShutdown {
+		t.Fatalf("unexpected cause chain %v", chain)
+	}
+	if val, _ := leaf.Read(); *val != errShutdown {
+		t.Fatalf("expected the root closeVal at the leaf")
+	}
+	if len(mid.CauseChain()) != 2 {
+		t.Fatalf("expected 2 causes at mid, got %v", mid.CauseChain())
+	}
+}
+
+func TestCauseChainTimeout(t *testing.T) {
+	root := loquet.NewChan[error](nil)
+	leaf := root.Child()
+	root.CloseAt(time.Now(), nil)
+
+	<-leaf.WhenClosed()
+	chain := leaf.CauseChain()
+	if len(chain) != 2 || chain[1] != context.DeadlineExceeded {
+		t.Fatalf("expected a deadline root cause, got %v", chain)
+	}
+
+	plain := loquet.NewChan[error](nil)
+	plain.Close()
+	if len(plain.CauseChain()) != 0 {
+		t.Fatalf("expected an empty chain for a plain Close")
+	}
+}
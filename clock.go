@@ -0,0 +1,39 @@
+package loquet
+
+import (
+	"time"
+)
+
+// Clock is the source of time for a Chan's timed
+// operations, such as CloseAt, and for the timestamps
+// in audit entries. By default a Chan uses the real
+// wall clock. Tests can inject a fake Clock with
+// WithClock to drive time deterministically,
+// without sleeping.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the default Clock, backed by the time package.
+type realClock struct{}
+
+func (realClock) Now() time.Time                         { return time.Now() }
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// WithClock makes the Chan use clock in place
+// of the real wall clock.
+func WithClock[T any](clock Clock) Option[T] {
+	return func(f *Chan[T]) {
+		f.clock = clock
+	}
+}
+
+// getClock returns the Chan's Clock.
+func (f *Chan[T]) getClock() Clock {
+	// clock is immutable after NewChan, no lock needed.
+	if f.clock == nil {
+		return realClock{}
+	}
+	return f.clock
+}
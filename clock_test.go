@@ -0,0 +1,93 @@
+package loquet_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+// fakeClock is a loquet.Clock that only moves
+// when Advance is called.
+type fakeClock struct {
+	mut     sync.Mutex
+	now     time.Time
+	waiters []fakeWaiter
+}
+
+type fakeWaiter struct {
+	at time.Time
+	ch chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	ch := make(chan time.Time, 1)
+	at := c.now.Add(d)
+	if d <= 0 {
+		ch <- c.now
+		return ch
+	}
+	c.waiters = append(c.waiters, fakeWaiter{at: at, ch: ch})
+	return ch
+}
+
+// Advance moves the clock forward by d, firing
+// any After channels that have come due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.now = c.now.Add(d)
+	keep := c.waiters[:0]
+	for _, w := range c.waiters {
+		if !w.at.After(c.now) {
+			w.ch <- c.now
+			continue
+		}
+		keep = append(keep, w)
+	}
+	c.waiters = keep
+}
+
+func TestCloseAtFakeClock(t *testing.T) {
+	clock := newFakeClock()
+	c := loquet.NewChan[Message](nil, loquet.WithClock[Message](clock))
+	timeout := &Message{Err: errTimeout}
+	c.CloseAt(clock.Now().Add(time.Hour), timeout)
+
+	clock.Advance(59 * time.Minute)
+	if _, isClosed := c.Read(); isClosed {
+		t.Fatalf("closed before the deadline")
+	}
+	clock.Advance(time.Minute)
+	<-c.WhenClosed()
+	if val, _ := c.Read(); val != timeout {
+		t.Fatalf("expected the timeout closeVal")
+	}
+}
+
+func TestAuditLogUsesClock(t *testing.T) {
+	clock := newFakeClock()
+	var when time.Time
+	c := loquet.NewChan[int](nil,
+		loquet.WithClock[int](clock),
+		loquet.WithAuditLog(func(e loquet.AuditEntry[int]) {
+			when = e.Time
+		}))
+	c.Close()
+	if !when.Equal(clock.Now()) {
+		t.Fatalf("expected audit Time %v from the fake clock, got %v", clock.Now(), when)
+	}
+}
@@ -0,0 +1,91 @@
+package loquet
+
+import (
+	"time"
+)
+
+// WithCoalesce limits how often a Set wakes the Chan's
+// waiters to at most once per minInterval, to protect
+// slow consumers from update storms. Each Set (or
+// Swap, SetFunc, and so on) still stores its closeVal
+// at once, so Read always sees the latest. But a Set
+// that follows the last notification by less than
+// minInterval is held back; when the interval is up,
+// the waiters are woken once, and see only the latest
+// closeVal, skipping those in between.
+//
+// The waiters affected are those woken by a Set:
+// the channels from SubscribeBuffered, WhenMatches
+// predicates, Updates, and the waiting methods such as
+// WaitUntil. Closes and resets are never held back,
+// and a close carries the final closeVal, superseding
+// any held Set, so the final value is always delivered
+// promptly. Audit entries, the Logger, history and the
+// other records still see every Set.
+//
+// The interval is measured on the Chan's Clock. While
+// a Set is held back, one goroutine waits out the
+// interval. A minInterval of zero or less
+// disables coalescing, which is the default.
+func WithCoalesce[T any](minInterval time.Duration) Option[T] {
+	return func(f *Chan[T]) {
+		f.coalesce = minInterval
+	}
+}
+
+// notifyLocked decides whether an event of the given
+// kind should wake the waiters now, or, for a Set
+// under WithCoalesce, be held back for flushCoalesced.
+// f.mut must be held.
+func (f *Chan[T]) notifyLocked(kind eventKind) bool {
+	if f.coalesce <= 0 {
+		return true
+	}
+	now := f.getClock().Now()
+	if kind == kindSet {
+		if wait := f.coalesce - now.Sub(f.lastNotify); wait > 0 {
+			f.held = true
+			if !f.flushing {
+				f.flushing = true
+				after := f.getClock().After(wait)
+				spawn(func() {
+					<-after
+					f.flushCoalesced()
+				})
+			}
+			return false
+		}
+	}
+	f.held = false
+	f.lastNotify = now
+	return true
+}
+
+// flushCoalesced wakes the waiters for a Set held
+// back by notifyLocked, if nothing has done so since.
+func (f *Chan[T]) flushCoalesced() {
+	f.mut.Lock()
+	defer f.unlock()
+	f.flushing = false
+	if !f.held {
+		return
+	}
+	f.held = false
+	f.lastNotify = f.getClock().Now()
+	if f.changed != nil {
+		close(f.changed)
+		f.changed = nil
+	}
+	bsubs := f.bsubsFor(kindSet)
+	matchers := f.matchersFor(kindSet)
+	if len(bsubs) == 0 && len(matchers) == 0 {
+		return
+	}
+	f.pending = append(f.pending, event[T]{
+		kind:     kindFlush,
+		new:      f.closeVal,
+		version:  f.version,
+		bsubs:    bsubs,
+		matchers: matchers,
+	})
+}
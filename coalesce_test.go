@@ -0,0 +1,61 @@
+package loquet_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestWithCoalesce(t *testing.T) {
+	clock := newFakeClock()
+	c := loquet.NewChan[int](nil,
+		loquet.WithClock[int](clock),
+		loquet.WithCoalesce[int](200*time.Millisecond),
+	)
+	ch, cancel := c.SubscribeBuffered(100)
+	defer cancel()
+
+	vals := make([]int, 10)
+	for i := range vals {
+		vals[i] = i
+		c.Set(&vals[i])
+	}
+	if got, _ := c.Read(); got != &vals[9] {
+		t.Fatalf("expected Read to see the latest Set at once")
+	}
+	if got := <-ch; got != &vals[0] {
+		t.Fatalf("expected the first Set notified at once, got %v", *got)
+	}
+	if n := len(ch); n != 0 {
+		t.Fatalf("expected later Sets held back, got %v", n)
+	}
+	clock.Advance(200 * time.Millisecond)
+	select {
+	case got := <-ch:
+		if got != &vals[9] {
+			t.Fatalf("expected only the latest Set after the interval, got %v", *got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the held Set to be flushed")
+	}
+
+	// a close is never held back.
+	final := 42
+	c.Set(&vals[1])
+	c.CloseWith(&final)
+	if n := len(ch); n != 1 {
+		t.Fatalf("expected the close delivered at once, got %v buffered", n)
+	}
+	if got := <-ch; got != &final {
+		t.Fatalf("expected the close delivered at once, got %v", *got)
+	}
+
+	// once the interval is up, the held Set is not
+	// flushed: the next value seen is a fresh Set.
+	clock.Advance(200 * time.Millisecond)
+	c.Set(&vals[2])
+	if got := <-ch; got != &vals[2] {
+		t.Fatalf("expected the held Set superseded by the close, got %v", *got)
+	}
+}
@@ -0,0 +1,236 @@
+package loquet
+
+// MergeFirst returns a new Chan that closes as soon
+// as the first of the sources closes, carrying the
+// closeVal of that winning source.
+//
+// One waiter goroutine is started per source.
+// Once the merged Chan closes, all of the
+// waiters exit, without waiting on the rest of
+// the sources. If several sources close at about the
+// same time, exactly one of them wins.
+//
+// Mirroring a select statement with no cases,
+// MergeFirst with zero sources returns a Chan
+// that never closes (unless the caller closes it).
+func MergeFirst[T any](sources ...*Chan[T]) (merged *Chan[T]) {
+	merged = NewChan[T](nil)
+	mergedClosed := merged.WhenClosed()
+	for _, src := range sources {
+		src.deriveAdd()
+		spawn(func() {
+			defer src.deriveDone()
+			select {
+			case <-src.WhenClosed():
+				val, _ := src.Read()
+				merged.CloseWith(val)
+			case <-mergedClosed:
+			}
+		})
+	}
+	return
+}
+
+// MergeAll returns a new Chan that closes only once
+// every one of the sources has closed. Its closeVal
+// is a slice holding the final closeVal of each
+// source, in the same order as the sources argument,
+// regardless of the order in which they closed.
+// This suits scatter-gather, where N jobs each
+// report on their own Chan, and one wants
+// a single Chan to wait on for all of the results.
+//
+// A single waiter goroutine is used. The closeVals
+// are read once all of the sources have closed.
+// The WhenClosed channels of the sources are taken at
+// the call, so a source that is closed and then reset
+// before the waiter runs still counts as closed.
+// With zero sources, the returned Chan is
+// already closed, carrying an empty slice.
+func MergeAll[T any](sources ...*Chan[T]) (merged *Chan[[]*T]) {
+	merged = NewChan[[]*T](nil)
+	if len(sources) == 0 {
+		merged.CloseWith(&[]*T{})
+		return
+	}
+	closed := make([]<-chan struct{}, len(sources))
+	for i, src := range sources {
+		closed[i] = src.WhenClosed()
+		src.deriveAdd()
+	}
+	spawn(func() {
+		defer func() {
+			for _, src := range sources {
+				src.deriveDone()
+			}
+		}()
+		for _, ch := range closed {
+			<-ch
+		}
+		vals := make([]*T, len(sources))
+		for i, src := range sources {
+			vals[i], _ = src.Read()
+		}
+		merged.CloseWith(&vals)
+	})
+	return
+}
+
+// Filter returns a new Chan that mirrors the next
+// close of src, but only if the closeVal satisfies pred.
+//
+// A single waiter goroutine waits for src to close,
+// and then calls pred with src's closeVal. If pred
+// returns true, the derived Chan is closed with that
+// same closeVal. If pred returns false, the derived
+// Chan stays open (until the caller closes it).
+// Either way the waiter then exits: Filter is
+// one-shot, and later resets and closes of
+// src are not considered. A pred that panics
+// counts as false; see OnCallbackPanic.
+func Filter[T any](src *Chan[T], pred func(*T) bool) (filtered *Chan[T]) {
+	filtered = NewChan[T](nil)
+	srcClosed := src.WhenClosed()
+	src.deriveAdd()
+	spawn(func() {
+		defer src.deriveDone()
+		<-srcClosed
+		val, _ := src.Read()
+		pass := false
+		protect(func() { pass = pred(val) })
+		if pass {
+			filtered.CloseWith(val)
+		}
+	})
+	return
+}
+
+// Pair carries the closeVals of the two
+// Chans combined by Zip.
+type Pair[A, B any] struct {
+	A *A
+	B *B
+}
+
+// Zip returns a new Chan that closes once both a and b
+// have closed, carrying a Pair of their final closeVals.
+// This generalizes the scatter-gather pattern of
+// MergeAll to results of two different types.
+//
+// A single waiter goroutine is used. The closeVals
+// are read once both inputs have closed.
+func Zip[A, B any](a *Chan[A], b *Chan[B]) (zipped *Chan[Pair[A, B]]) {
+	zipped = NewChan[Pair[A, B]](nil)
+	aClosed := a.WhenClosed()
+	bClosed := b.WhenClosed()
+	a.deriveAdd()
+	b.deriveAdd()
+	spawn(func() {
+		defer a.deriveDone()
+		defer b.deriveDone()
+		<-aClosed
+		<-bClosed
+		var pair Pair[A, B]
+		pair.A, _ = a.Read()
+		pair.B, _ = b.Read()
+		zipped.CloseWith(&pair)
+	})
+	return
+}
+
+// Tee returns two new Chans, a and b, that each close
+// with src's closeVal when src next closes. This
+// gives two subsystems their own handles on the same
+// event, which each may then reset, reopen, or
+// otherwise manipulate without affecting the other.
+//
+// A single waiter goroutine closes both. Like Filter,
+// Tee is one-shot: later resets and closes of src
+// are not forwarded. If src is already closed,
+// a and b close promptly too. Closing a or b
+// directly does not stop the waiter, which
+// exits only once src closes.
+func Tee[T any](src *Chan[T]) (a, b *Chan[T]) {
+	a = NewChan[T](nil)
+	b = NewChan[T](nil)
+	srcClosed := src.WhenClosed()
+	src.deriveAdd()
+	spawn(func() {
+		defer src.deriveDone()
+		<-srcClosed
+		val, _ := src.Read()
+		a.CloseWith(val)
+		b.CloseWith(val)
+	})
+	return
+}
+
+// FlatMap chains two asynchronous steps, where the
+// result of the first decides what to wait on next.
+// When src closes, fn is called with src's closeVal
+// to obtain a second Chan, and the close of that Chan,
+// with its closeVal, is then forwarded to the
+// returned Chan, flat.
+//
+// A single waiter goroutine is used. If fn returns
+// nil, or panics (see OnCallbackPanic), flat stays
+// open (until the caller closes it).
+// Like Filter, FlatMap is one-shot, and considers
+// only the next close of src and of the Chan from fn.
+// For WaitClosePropagated, the waiter counts as
+// a derivation of src until fn has returned, and
+// then as a derivation of fn's Chan.
+func FlatMap[A, B any](src *Chan[A], fn func(*A) *Chan[B]) (flat *Chan[B]) {
+	flat = NewChan[B](nil)
+	srcClosed := src.WhenClosed()
+	src.deriveAdd()
+	spawn(func() {
+		<-srcClosed
+		val, _ := src.Read()
+		var next *Chan[B]
+		protect(func() { next = fn(val) })
+		if next == nil {
+			src.deriveDone()
+			return
+		}
+		next.deriveAdd()
+		defer next.deriveDone()
+		nextClosed := next.WhenClosed()
+		src.deriveDone()
+
+		<-nextClosed
+		nval, _ := next.Read()
+		flat.CloseWith(nval)
+	})
+	return
+}
+
+// Mirror returns a fresh Chan that closes with f's
+// closeVal when f next closes. Mirror may be called
+// any number of times, at any time, so that consumers
+// can join a broadcast whenever they like, each with
+// a Chan of their own to reset or reopen at will.
+//
+// Unlike Tee and the other combinators, Mirror
+// spends no goroutine: each mirror is registered with
+// f, closed by the goroutine that closes f, after
+// f's mutex has been released, and unregistered as
+// it fires. Mirrors are thus one-shot, and are not
+// affected by later resets of f. A mirror of a
+// Chan that is already closed is returned closed.
+// A mirror that is closed independently stays
+// registered until f closes, at which point the
+// attempt to close it again is ignored.
+func (f *Chan[T]) Mirror() (mirror *Chan[T]) {
+	mirror = NewChan[T](nil)
+	f.mut.Lock()
+	if !f.isClosed || f.marked {
+		f.mirrors = append(f.mirrors, mirror)
+		f.mut.Unlock()
+		return
+	}
+	val := f.closeVal
+	f.mut.Unlock()
+	mirror.CloseWith(val)
+	return
+}
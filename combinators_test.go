@@ -0,0 +1,190 @@
+package loquet_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestMergeFirst(t *testing.T) {
+	a := loquet.NewChan[int](nil)
+	b := loquet.NewChan[int](nil)
+	c := loquet.NewChan[int](nil)
+	merged := loquet.MergeFirst(a, b, c)
+
+	two := 2
+	b.CloseWith(&two)
+
+	select {
+	case <-merged.WhenClosed():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("merged Chan never closed")
+	}
+	if val, _ := merged.Read(); *val != 2 {
+		t.Fatalf("expected merged closeVal 2, got %v", *val)
+	}
+
+	none := loquet.MergeFirst[int]()
+	if _, isClosed := none.Read(); isClosed {
+		t.Fatalf("MergeFirst of no sources should never close")
+	}
+}
+
+func TestMergeAllKeepsSourceOrder(t *testing.T) {
+	srcs := []*loquet.Chan[int]{
+		loquet.NewChan[int](nil),
+		loquet.NewChan[int](nil),
+		loquet.NewChan[int](nil),
+	}
+	merged := loquet.MergeAll(srcs...)
+
+	// complete in reverse order.
+	for i := len(srcs) - 1; i >= 0; i-- {
+		if _, isClosed := merged.Read(); isClosed {
+			t.Fatalf("merged closed before all sources closed")
+		}
+		v := i * 10
+		srcs[i].CloseWith(&v)
+	}
+
+	select {
+	case <-merged.WhenClosed():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("merged Chan never closed")
+	}
+	vals, _ := merged.Read()
+	for i, v := range *vals {
+		if *v != i*10 {
+			t.Fatalf("expected result %v to be %v, got %v", i, i*10, *v)
+		}
+	}
+}
+
+func TestMergeAllNoSources(t *testing.T) {
+	merged := loquet.MergeAll[int]()
+	vals, isClosed := merged.Read()
+	if !isClosed || vals == nil || len(*vals) != 0 {
+		t.Fatalf("expected closed at once with an empty slice")
+	}
+}
+
+func TestMergeAllSeesCloseBeforeReset(t *testing.T) {
+	src := loquet.NewChan[int](nil)
+	src.Close()
+	merged := loquet.MergeAll(src)
+	src.Reset(nil) // possibly before the waiter runs.
+	waitClosed(t, merged)
+}
+
+func TestFilter(t *testing.T) {
+	isEven := func(v *int) bool { return v != nil && *v%2 == 0 }
+
+	src := loquet.NewChan[int](nil)
+	even := loquet.Filter(src, isEven)
+	two := 2
+	src.CloseWith(&two)
+	waitClosed(t, even)
+	if val, _ := even.Read(); *val != 2 {
+		t.Fatalf("expected filtered closeVal 2, got %v", *val)
+	}
+
+	src = loquet.NewChan[int](nil)
+	even = loquet.Filter(src, isEven)
+	three := 3
+	src.CloseWith(&three)
+	time.Sleep(20 * time.Millisecond)
+	if _, isClosed := even.Read(); isClosed {
+		t.Fatalf("filtered Chan should stay open when pred is false")
+	}
+}
+
+func TestZip(t *testing.T) {
+	a := loquet.NewChan[int](nil)
+	b := loquet.NewChan[string](nil)
+	zipped := loquet.Zip(a, b)
+
+	s := "done"
+	b.CloseWith(&s)
+	time.Sleep(10 * time.Millisecond)
+	if _, isClosed := zipped.Read(); isClosed {
+		t.Fatalf("zipped Chan closed before both inputs closed")
+	}
+	n := 42
+	a.CloseWith(&n)
+	waitClosed(t, zipped)
+	pair, _ := zipped.Read()
+	if *pair.A != 42 || *pair.B != "done" {
+		t.Fatalf("unexpected pair %v, %v", *pair.A, *pair.B)
+	}
+}
+
+func TestTee(t *testing.T) {
+	src := loquet.NewChan[int](nil)
+	a, b := loquet.Tee(src)
+	seven := 7
+	src.CloseWith(&seven)
+	waitClosed(t, a)
+	waitClosed(t, b)
+	va, _ := a.Read()
+	vb, _ := b.Read()
+	if va != &seven || vb != &seven {
+		t.Fatalf("expected both Chans to carry the source closeVal")
+	}
+
+	// a and b are independent handles.
+	a.ReadAndReset(nil)
+	if _, isClosed := b.Read(); !isClosed {
+		t.Fatalf("resetting a must not affect b")
+	}
+}
+
+func TestFlatMap(t *testing.T) {
+	lookup := loquet.NewChan[string](nil)
+	steps := map[string]*loquet.Chan[int]{
+		"fast": loquet.NewChan[int](nil),
+	}
+	flat := loquet.FlatMap(lookup, func(key *string) *loquet.Chan[int] {
+		return steps[*key]
+	})
+
+	key := "fast"
+	lookup.CloseWith(&key)
+	lookup.WaitClosePropagated()
+	if _, isClosed := flat.Read(); isClosed {
+		t.Fatalf("flat must wait for the second step")
+	}
+
+	answer := 42
+	steps["fast"].CloseWith(&answer)
+	steps["fast"].WaitClosePropagated()
+	if val, isClosed := flat.Read(); !isClosed || *val != 42 {
+		t.Fatalf("expected flat to close with 42")
+	}
+}
+
+func TestMirror(t *testing.T) {
+	src := loquet.NewChan[int](nil)
+	var mirrors []*loquet.Chan[int]
+	for i := 0; i < 3; i++ {
+		mirrors = append(mirrors, src.Mirror())
+	}
+	five := 5
+	src.CloseWith(&five) // closes the mirrors before returning.
+	for i, m := range mirrors {
+		if val, isClosed := m.Read(); !isClosed || val != &five {
+			t.Fatalf("mirror %v: expected closed with 5", i)
+		}
+	}
+
+	// mirrors fire once; a late mirror of a closed Chan is closed.
+	mirrors[0].ReadAndReset(nil)
+	src.ReadAndReset(nil)
+	src.Close()
+	if mirrors[0].Closed() {
+		t.Fatalf("a mirror must be unregistered after firing")
+	}
+	if !src.Mirror().Closed() {
+		t.Fatalf("expected a mirror of a closed Chan to be closed")
+	}
+}
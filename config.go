@@ -0,0 +1,70 @@
+package loquet
+
+import (
+	"sync"
+)
+
+// NewConfigChan returns a Chan whose closeVal mirrors
+// a configuration source, making the Chan a live-config
+// broadcast primitive. Readers simply call Read()
+// whenever they want the current configuration.
+//
+// The initial closeVal is obtained by calling load
+// before NewConfigChan returns. Thereafter, each
+// time the reload channel fires, load is called
+// again and a successful result is stored with Set.
+//
+// If load returns an error, the last good closeVal
+// is kept, and the error is reported to onErr, if
+// onErr is not nil. An error on the initial load
+// leaves the closeVal nil. On the reloads, which run
+// on the reload goroutine, a panic in load or onErr
+// is recovered and reported to OnCallbackPanic, and
+// the last good closeVal is kept.
+//
+// The returned stop func ends the reload goroutine.
+// It is safe to call stop more than once. Closing the
+// reload channel also ends the reload goroutine. Neither
+// closes the returned Chan; that is left to the user.
+func NewConfigChan[T any](load func() (*T, error), reload <-chan struct{}, onErr func(error)) (f *Chan[T], stop func()) {
+
+	f = NewChan[T](nil)
+	cur, err := load()
+	if err != nil {
+		if onErr != nil {
+			onErr(err)
+		}
+	} else {
+		f.Set(cur)
+	}
+
+	done := make(chan struct{})
+	var once sync.Once
+	stop = func() {
+		once.Do(func() { close(done) })
+	}
+
+	spawn(func() {
+		for {
+			select {
+			case _, ok := <-reload:
+				if !ok {
+					return
+				}
+				protect(func() {
+					cur, err := load()
+					if err != nil {
+						if onErr != nil {
+							onErr(err)
+						}
+						return
+					}
+					f.Set(cur)
+				})
+			case <-done:
+				return
+			}
+		}
+	})
+	return
+}
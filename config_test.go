@@ -0,0 +1,78 @@
+package loquet_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+type Config struct {
+	Level int
+}
+
+// eventually polls cond until it returns true,
+// failing the test after a few seconds.
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("condition never became true")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestConfigChanReload(t *testing.T) {
+	level := 0
+	load := func() (*Config, error) {
+		level++
+		return &Config{Level: level}, nil
+	}
+	reload := make(chan struct{})
+	cfg, stop := loquet.NewConfigChan(load, reload, nil)
+	defer stop()
+
+	val, _ := cfg.Read()
+	if val.Level != 1 {
+		t.Fatalf("expected initial Level 1, got %v", val.Level)
+	}
+	reload <- struct{}{}
+	eventually(t, func() bool {
+		val, _ := cfg.Read()
+		return val.Level == 2
+	})
+}
+
+func TestConfigChanReloadErrorKeepsLastGood(t *testing.T) {
+	fail := false
+	load := func() (*Config, error) {
+		if fail {
+			return nil, fmt.Errorf("bad config")
+		}
+		return &Config{Level: 7}, nil
+	}
+	errs := make(chan error, 1)
+	reload := make(chan struct{})
+	cfg, stop := loquet.NewConfigChan(load, reload, func(err error) {
+		errs <- err
+	})
+	defer stop()
+
+	fail = true
+	reload <- struct{}{}
+	select {
+	case err := <-errs:
+		if err == nil {
+			t.Fatalf("expected a load error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("onErr was never called")
+	}
+	val, isClosed := cfg.Read()
+	if val.Level != 7 || isClosed {
+		t.Fatalf("expected last good Level 7 and open, got %v, %v", val.Level, isClosed)
+	}
+}
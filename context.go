@@ -0,0 +1,46 @@
+package loquet
+
+import (
+	"context"
+)
+
+// BindAfterFunc arranges for the Chan to CloseWith(val)
+// once ctx is done, using context.AfterFunc. Unlike
+// an approach that parks a goroutine on ctx.Done(),
+// BindAfterFunc does not spawn a dedicated goroutine:
+// AfterFunc reuses the runtime's cancellation
+// machinery, and only starts a goroutine to run
+// the close once ctx is actually done.
+//
+// The returned stop is the stop func from
+// context.AfterFunc. Calling it before ctx is done
+// prevents the close, and reports true; it reports
+// false if the close has already been started, or
+// stop was already called.
+func (f *Chan[T]) BindAfterFunc(ctx context.Context, val *T) (stop func() bool) {
+	return context.AfterFunc(ctx, func() {
+		f.CloseWith(val)
+	})
+}
+
+// chanKey is the context key for a *Chan[T]. Being
+// generic, it is a distinct key for each T, so a
+// context can carry one Chan of each type.
+type chanKey[T any] struct{}
+
+// WithChan returns a copy of ctx that carries f, for
+// passing a status Chan through middleware chains.
+// Retrieve it with ChanFrom. Since the key is
+// unexported and typed, no other package can
+// collide with it, or retrieve f as the wrong type.
+func WithChan[T any](ctx context.Context, f *Chan[T]) context.Context {
+	return context.WithValue(ctx, chanKey[T]{}, f)
+}
+
+// ChanFrom returns the Chan[T] stored in ctx by
+// WithChan, with ok true, or nil and false if
+// ctx carries no Chan of that type.
+func ChanFrom[T any](ctx context.Context) (f *Chan[T], ok bool) {
+	f, ok = ctx.Value(chanKey[T]{}).(*Chan[T])
+	return
+}
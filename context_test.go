@@ -0,0 +1,57 @@
+package loquet_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestBindAfterFunc(t *testing.T) {
+	cancelled := &Message{Err: context.Canceled}
+	c := loquet.NewChan[Message](nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	c.BindAfterFunc(ctx, cancelled)
+
+	cancel()
+	select {
+	case <-c.WhenClosed():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Chan never closed after ctx was cancelled")
+	}
+	if val, _ := c.Read(); val != cancelled {
+		t.Fatalf("expected the bound closeVal")
+	}
+}
+
+func TestBindAfterFuncStop(t *testing.T) {
+	c := loquet.NewChan[Message](nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	stop := c.BindAfterFunc(ctx, &Message{})
+
+	if !stop() {
+		t.Fatalf("expected stop to report it prevented the close")
+	}
+	cancel()
+	time.Sleep(10 * time.Millisecond)
+	if _, isClosed := c.Read(); isClosed {
+		t.Fatalf("stop should have prevented the close")
+	}
+}
+
+func TestWithChanAndChanFrom(t *testing.T) {
+	status := loquet.NewChan[Message](nil)
+	ctx := loquet.WithChan(context.Background(), status)
+
+	got, ok := loquet.ChanFrom[Message](ctx)
+	if !ok || got != status {
+		t.Fatalf("expected to retrieve the stored Chan")
+	}
+	if _, ok := loquet.ChanFrom[int](ctx); ok {
+		t.Fatalf("expected no Chan[int] in the context")
+	}
+	if _, ok := loquet.ChanFrom[Message](context.Background()); ok {
+		t.Fatalf("expected no Chan in an empty context")
+	}
+}
@@ -0,0 +1,65 @@
+package loquet
+
+// Add adds delta, which may be negative, to the Chan's
+// counter, and closes the Chan, as Close does, when the
+// counter reaches zero. Together with Done, this is
+// sync.WaitGroup with a broadcast: the coordinator
+// calls Add(n) before starting n workers, each worker
+// calls Done when it finishes, and any number of
+// watchers wait on WhenClosed, then Read the closeVal,
+// which the workers may have updated with Set along
+// the way:
+//
+//	allDone := loquet.NewChan[Summary](&Summary{})
+//	allDone.Add(len(jobs))
+//	for _, job := range jobs {
+//	    go func() {
+//	        defer allDone.Done()
+//	        run(job)
+//	    }()
+//	}
+//	<-allDone.WhenClosed()
+//
+// As with a WaitGroup, the calls with a positive delta
+// that start when the counter is zero must happen before
+// the Chan is waited on. If the counter goes negative,
+// Add panics. The Chan starts with a counter of zero,
+// but is not closed by that alone: only an Add that
+// brings the counter down to zero closes it. A Chan
+// that is already closed at that point is left as is,
+// and the counter can be used again once it is reset.
+// On a frozen Chan (see Freeze), the counter is
+// left alone, and Add does nothing.
+func (f *Chan[T]) Add(delta int) {
+	f.add(delta, "Add")
+}
+
+// Done decrements the Chan's counter by one,
+// closing the Chan if it reaches zero. See Add.
+func (f *Chan[T]) Done() {
+	f.add(-1, "Done")
+}
+
+// add implements Add and Done, naming the
+// close, if any, after op.
+func (f *Chan[T]) add(delta int, op string) {
+	f.mut.Lock()
+	defer f.unlock()
+
+	if f.frozen {
+		return
+	}
+	f.count += delta
+	if f.count < 0 {
+		f.count = 0
+		panic("loquet: negative Chan counter")
+	}
+	if f.count > 0 || delta >= 0 {
+		return
+	}
+	if f.isClosed && !f.marked {
+		return
+	}
+	f.closePlainLocked()
+	f.recordLocked(kindClose, op, f.closeVal)
+}
@@ -0,0 +1,69 @@
+package loquet_test
+
+import (
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestAddDone(t *testing.T) {
+	total := 0
+	c := loquet.NewChan[int](&total)
+	c.Add(3)
+	for i := 0; i < 3; i++ {
+		go c.Done()
+	}
+	waitClosed(t, c)
+	if val, _ := c.Read(); val != &total {
+		t.Fatalf("expected the closeVal kept, as by Close")
+	}
+
+	// the counter can be used again after a reset.
+	c.Reset(nil)
+	c.Add(2)
+	c.Done()
+	if _, isClosed := c.Read(); isClosed {
+		t.Fatalf("expected open while the counter is positive")
+	}
+	c.Done()
+	if _, isClosed := c.Read(); !isClosed {
+		t.Fatalf("expected closed once the counter reached zero")
+	}
+}
+
+func TestDoneNegativePanics(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected a panic on a negative counter")
+		}
+		if _, isClosed := c.Read(); isClosed {
+			t.Fatalf("expected the Chan left open")
+		}
+	}()
+	c.Done()
+}
+
+func TestAddNamesTheOperation(t *testing.T) {
+	var ops []string
+	c := loquet.NewChan[int](nil, loquet.WithAuditLog(func(e loquet.AuditEntry[int]) {
+		ops = append(ops, e.Op)
+	}))
+	c.Add(2)
+	c.Add(-2)
+	c.Reset(nil)
+	c.Add(1)
+	c.Done()
+	if len(ops) != 3 || ops[0] != "Add" || ops[2] != "Done" {
+		t.Fatalf("expected the closes named Add and Done, got %v", ops)
+	}
+}
+
+func TestAddFrozen(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	c.Freeze()
+	c.Done() // the counter is left alone: no panic.
+	if c.Closed() {
+		t.Fatalf("expected a frozen Chan left open")
+	}
+}
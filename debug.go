@@ -0,0 +1,168 @@
+package loquet
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DebugChecks, when true, makes every Chan verify its
+// internal invariants under its mutex after each
+// mutating operation, and on each Read, panicking
+// if any are violated. The invariants are:
+//
+//   - the WhenClosed channel is closed if and
+//     only if the Chan reports isClosed, save
+//     for a Chan marked closed by MarkClosed;
+//   - Closed() agrees with isClosed;
+//   - the version never decreases;
+//   - with WithNotifyChannelGuard, no issued
+//     channel is left pending on a closed Chan,
+//     again save for one marked closed.
+//
+// This is meant for tests, especially heavy
+// concurrent tests run under the race detector,
+// of this package and of wrappers built upon it.
+// Set DebugChecks before creating any Chans (for
+// example in TestMain), and do not change it while
+// Chans are in use. It is off by default, and then
+// costs only a branch per operation.
+var DebugChecks bool
+
+// checkLocked panics if any of the invariants
+// documented on DebugChecks is violated. op names
+// the operation that was just performed.
+// f.mut must be held, at least for reading.
+func (f *Chan[T]) checkLocked(op string) {
+	chClosed := false
+	select {
+	case <-f.whenClosed:
+		chClosed = true
+	default:
+	}
+	if chClosed != (f.isClosed && !f.marked) {
+		panic(fmt.Sprintf("loquet: invariant violated after %v: "+
+			"WhenClosed channel closed=%v but isClosed=%v",
+			op, chClosed, f.isClosed))
+	}
+	if f.closed.Load() != f.isClosed {
+		panic(fmt.Sprintf("loquet: invariant violated after %v: "+
+			"Closed()=%v but isClosed=%v",
+			op, f.closed.Load(), f.isClosed))
+	}
+	if checked := f.checkedVersion.Load(); f.version < checked {
+		panic(fmt.Sprintf("loquet: invariant violated after %v: "+
+			"version went backwards from %v to %v",
+			op, checked, f.version))
+	}
+	f.checkedVersion.Store(f.version)
+	if f.guard != nil && f.isClosed && !f.marked && len(f.guard.issued) > 0 {
+		panic(fmt.Sprintf("loquet: invariant violated after %v: "+
+			"%v guarded WhenClosed channels left open on a closed Chan",
+			op, len(f.guard.issued)))
+	}
+}
+
+// DeadlockTimeout, when positive, bounds how long any
+// Chan method will wait to acquire the Chan's mutex.
+// Past that, the method panics with a message naming
+// the likely deadlock, followed by the stacks of all
+// goroutines, which should show who holds the mutex.
+// The usual culprit is a callback, such as a
+// WithLockHeld fn or a custom Clock, that calls back
+// into the same Chan.
+//
+// Like DebugChecks, this is meant for tests, and is
+// best set in TestMain, before any Chans are created.
+// Choose a timeout well beyond any legitimate wait:
+// a mutex is only ever held briefly, but under the
+// race detector on a loaded machine, briefly can
+// still mean many milliseconds. It is zero (off) by
+// default; the cost when off is a branch per
+// acquisition. When on, the mutex is polled with
+// TryLock, so contended acquisitions get slower,
+// and fairness is lost.
+var DeadlockTimeout time.Duration
+
+// rwMutex is a sync.RWMutex whose Lock and RLock
+// observe DeadlockTimeout.
+type rwMutex struct {
+	sync.RWMutex
+}
+
+func (m *rwMutex) Lock() {
+	if DeadlockTimeout <= 0 {
+		m.RWMutex.Lock()
+		return
+	}
+	timedLock(m.RWMutex.TryLock, "Lock")
+}
+
+func (m *rwMutex) RLock() {
+	if DeadlockTimeout <= 0 {
+		m.RWMutex.RLock()
+		return
+	}
+	timedLock(m.RWMutex.TryRLock, "RLock")
+}
+
+// timedLock polls try, backing off up to a millisecond
+// between attempts, until it succeeds, or panics once
+// DeadlockTimeout has passed.
+func timedLock(try func() bool, op string) {
+	limit := DeadlockTimeout
+	deadline := time.Now().Add(limit)
+	pause := time.Microsecond
+	for !try() {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			buf = buf[:runtime.Stack(buf, true)]
+			panic(fmt.Sprintf("loquet: likely deadlock: %v on a "+
+				"Chan's mutex did not succeed within "+
+				"DeadlockTimeout (%v). All goroutines:\n\n%s",
+				op, limit, buf))
+		}
+		time.Sleep(pause)
+		if pause < time.Millisecond {
+			pause *= 2
+		}
+	}
+}
+
+// TrackGoroutines, when true, makes the package count
+// the goroutines it starts on behalf of users (for
+// CloseAt, WhenClosedFunc, MergeFirst, Child, and so on),
+// so that tests can assert, via ActiveGoroutines,
+// that none are leaked. Like DebugChecks, set it
+// before use, for example in TestMain. It is off
+// by default; the cost when on is an atomic add
+// as each goroutine starts and exits.
+var TrackGoroutines bool
+
+// activeGoroutines is the count behind ActiveGoroutines.
+var activeGoroutines atomic.Int64
+
+// ActiveGoroutines returns the number of goroutines
+// started by this package, while TrackGoroutines
+// was true, that have not yet exited.
+func ActiveGoroutines() int {
+	return int(activeGoroutines.Load())
+}
+
+// spawn starts fn in a new goroutine, counting
+// it in ActiveGoroutines if TrackGoroutines is on.
+// Every goroutine the package starts goes
+// through spawn.
+func spawn(fn func()) {
+	if !TrackGoroutines {
+		go fn()
+		return
+	}
+	activeGoroutines.Add(1)
+	go func() {
+		defer activeGoroutines.Add(-1)
+		fn()
+	}()
+}
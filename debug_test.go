@@ -0,0 +1,154 @@
+package loquet
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMain(m *testing.M) {
+	// run the whole suite with invariant checking
+	// and goroutine tracking on.
+	DebugChecks = true
+	TrackGoroutines = true
+	os.Exit(m.Run())
+}
+
+func expectInvariantPanic(t *testing.T, op func()) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatalf("expected an invariant panic")
+		}
+		if !strings.Contains(r.(string), "invariant violated") {
+			t.Fatalf("unexpected panic: %v", r)
+		}
+	}()
+	op()
+}
+
+func TestDebugChecksClosedMismatch(t *testing.T) {
+	f := NewChan[int](nil)
+	f.isClosed = true // corrupt: whenClosed is still open.
+	expectInvariantPanic(t, func() { f.Read() })
+}
+
+func TestDebugChecksVersionBackwards(t *testing.T) {
+	f := NewChan[int](nil)
+	f.Set(nil)
+	f.version = -5 // corrupt.
+	expectInvariantPanic(t, func() { f.Set(nil) })
+}
+
+func TestActiveGoroutinesNoLeak(t *testing.T) {
+	waitFor := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for ActiveGoroutines() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %v active goroutines, got %v", want, ActiveGoroutines())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	base := ActiveGoroutines()
+
+	f := NewChan[int](nil)
+	cancel := f.CloseAt(time.Now().Add(time.Hour), nil)
+	stop := f.WhenClosedFunc(func(*int) {})
+	child := f.Child()
+	waitFor(base + 3)
+
+	cancel()
+	stop()
+	child.Close()
+	waitFor(base)
+}
+
+func TestMarkClosedKeepsInvariants(t *testing.T) {
+	c := NewChan[int](nil)
+	c.MarkClosed()
+	c.Read() // checked under DebugChecks.
+	c.ReadAndReset(nil)
+	c.Close()
+}
+
+func expectDeadlockPanic(t *testing.T, op func()) {
+	t.Helper()
+	defer func() {
+		r := recover()
+		if r == nil {
+			t.Fatalf("expected a deadlock panic")
+		}
+		msg := r.(string)
+		if !strings.Contains(msg, "likely deadlock") ||
+			!strings.Contains(msg, "goroutine ") {
+			t.Fatalf("unexpected panic: %v", r)
+		}
+	}()
+	op()
+}
+
+func TestDeadlockTimeout(t *testing.T) {
+	DeadlockTimeout = 50 * time.Millisecond
+	defer func() { DeadlockTimeout = 0 }()
+
+	c := NewChan[int](nil)
+	c.Set(nil) // uncontended: fine.
+	c.Read()   // the first Read takes the write lock.
+
+	c.mut.Lock()
+	expectDeadlockPanic(t, func() { c.Set(nil) })
+	expectDeadlockPanic(t, func() { c.Read() })
+	c.mut.Unlock()
+
+	// a reader holding on does not stop other readers.
+	c.mut.RLock()
+	c.Read()
+	expectDeadlockPanic(t, func() { c.Close() })
+	c.mut.RUnlock()
+
+	// contended, but released in time.
+	c.mut.Lock()
+	time.AfterFunc(10*time.Millisecond, c.mut.Unlock)
+	c.Close()
+	if !c.Closed() {
+		t.Fatalf("expected closed")
+	}
+}
+
+// TestReadersStayOnReadLock checks that neither a Read
+// that observes a close, nor a waiter sharing an existing
+// changed channel, needs the write lock: both complete
+// while another reader holds the read lock, which
+// would block any writer.
+func TestReadersStayOnReadLock(t *testing.T) {
+	c := NewChan[int](nil)
+	c.Read()         // the first Read takes the write lock.
+	c.readAndWatch() // the first waiter allocates changed.
+
+	completes := func(what string, op func()) {
+		t.Helper()
+		c.mut.RLock()
+		defer c.mut.RUnlock()
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			op()
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%v blocked behind a held read lock", what)
+		}
+	}
+	completes("a second waiter", func() { c.readAndWatch() })
+
+	c.Close()
+	completes("the first Read after a close", func() { c.Read() })
+	if !c.observed.Load() {
+		t.Fatalf("expected the close marked observed")
+	}
+}
@@ -0,0 +1,66 @@
+package loquet
+
+import (
+	"sync"
+)
+
+// SubscribeDeltas observes a Chan[float64] tracking
+// a metric, delivering on ch the change amount
+// (new - previous) each time the closeVal changes,
+// rather than the new value itself. The closeVal
+// at the time of the call is the baseline for
+// the first delta. This is handy for rate and
+// derivative computations.
+//
+// A nil closeVal is treated as 0. Changes that
+// leave the value the same, such as a Close(),
+// produce no delta.
+//
+// If several changes happen faster than the
+// receiver keeps up, they are coalesced into a
+// single delta. The deltas received therefore
+// always sum to the total change since the baseline.
+//
+// The returned cancel func stops the delivery
+// goroutine and closes ch. It is safe to call
+// more than once.
+func SubscribeDeltas(c *Chan[float64]) (ch <-chan float64, cancel func()) {
+	out := make(chan float64)
+	done := make(chan struct{})
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() { close(done) })
+	}
+
+	valueOf := func(p *float64) float64 {
+		if p == nil {
+			return 0
+		}
+		return *p
+	}
+	cur, _, changed := c.readAndWatch()
+	prev := valueOf(cur)
+
+	spawn(func() {
+		defer close(out)
+		for {
+			select {
+			case <-changed:
+			case <-done:
+				return
+			}
+			cur, _, changed = c.readAndWatch()
+			delta := valueOf(cur) - prev
+			if delta == 0 {
+				continue
+			}
+			select {
+			case out <- delta:
+				prev = valueOf(cur)
+			case <-done:
+				return
+			}
+		}
+	})
+	return out, cancel
+}
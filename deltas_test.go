@@ -0,0 +1,29 @@
+package loquet_test
+
+import (
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestSubscribeDeltas(t *testing.T) {
+	start := 10.0
+	c := loquet.NewChan(&start)
+	deltas, cancel := loquet.SubscribeDeltas(c)
+
+	for i, step := range []struct{ set, delta float64 }{
+		{12, 2},
+		{11.5, -0.5},
+		{20, 8.5},
+	} {
+		v := step.set
+		c.Set(&v)
+		if got := <-deltas; got != step.delta {
+			t.Fatalf("step %v: expected delta %v, got %v", i, step.delta, got)
+		}
+	}
+	cancel()
+	for range deltas {
+		// drain until closed by cancel.
+	}
+}
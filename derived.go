@@ -0,0 +1,72 @@
+package loquet
+
+import (
+	"sync"
+)
+
+// DerivedScalar returns a func that, on each call,
+// reads the current closeVal of c and returns
+// compute(closeVal). This suits exposing a gauge,
+// such as a health score, derived from a
+// structured closeVal.
+//
+// compute may be passed a nil closeVal.
+func DerivedScalar[T any](c *Chan[T], compute func(*T) float64) func() float64 {
+	return func() float64 {
+		val, _ := c.Read()
+		return compute(val)
+	}
+}
+
+// WatchDerivedScalar is the push variant of DerivedScalar.
+// It delivers compute(closeVal) on ch, first for the
+// closeVal at the time of the call, and then each
+// time a change to c produces a different scalar.
+//
+// Changes arriving faster than the receiver keeps
+// up are coalesced, so the receiver always ends up
+// with the scalar for the latest closeVal, but may
+// not see every intermediate one. A closeVal for
+// which compute panics is skipped; see OnCallbackPanic.
+//
+// The returned cancel func stops the watching
+// goroutine and closes ch. It is safe to call
+// more than once.
+func WatchDerivedScalar[T any](c *Chan[T], compute func(*T) float64) (ch <-chan float64, cancel func()) {
+	out := make(chan float64)
+	done := make(chan struct{})
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() { close(done) })
+	}
+
+	spawn(func() {
+		defer close(out)
+		first := true
+		var last float64
+		for {
+			val, _, changed := c.readAndWatch()
+			var scalar float64
+			ok := false
+			protect(func() {
+				scalar = compute(val)
+				ok = true
+			})
+			if ok && (first || scalar != last) {
+				select {
+				case out <- scalar:
+					first = false
+					last = scalar
+				case <-done:
+					return
+				}
+			}
+			select {
+			case <-changed:
+			case <-done:
+				return
+			}
+		}
+	})
+	return out, cancel
+}
@@ -0,0 +1,44 @@
+package loquet_test
+
+import (
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+type Health struct {
+	Healthy, Total int
+}
+
+func healthScore(h *Health) float64 {
+	if h == nil || h.Total == 0 {
+		return 0
+	}
+	return float64(h.Healthy) / float64(h.Total)
+}
+
+func TestDerivedScalar(t *testing.T) {
+	c := loquet.NewChan(&Health{Healthy: 1, Total: 4})
+	score := loquet.DerivedScalar(c, healthScore)
+	if got := score(); got != 0.25 {
+		t.Fatalf("expected 0.25, got %v", got)
+	}
+	c.Set(&Health{Healthy: 3, Total: 4})
+	if got := score(); got != 0.75 {
+		t.Fatalf("expected 0.75, got %v", got)
+	}
+}
+
+func TestWatchDerivedScalar(t *testing.T) {
+	c := loquet.NewChan(&Health{Healthy: 1, Total: 2})
+	scores, cancel := loquet.WatchDerivedScalar(c, healthScore)
+	defer cancel()
+
+	if got := <-scores; got != 0.5 {
+		t.Fatalf("expected initial 0.5, got %v", got)
+	}
+	c.Set(&Health{Healthy: 2, Total: 2})
+	if got := <-scores; got != 1 {
+		t.Fatalf("expected 1 after Set, got %v", got)
+	}
+}
@@ -0,0 +1,70 @@
+package loquet
+
+import (
+	"context"
+	"sync"
+)
+
+// DynamicAll waits for close across a set of Chans that
+// may keep growing while the wait is in progress, as
+// happens with structured concurrency where new tasks
+// are spawned (and registered) late.
+//
+// Chans are registered with Add, which may be called at
+// any time, including after Wait has started. Wait
+// completes once every Chan added so far has closed.
+//
+// Completion is final: the first time any Wait observes
+// that all added Chans are closed, the DynamicAll is
+// done, and any later Add is rejected with
+// ErrDynamicAllDone. Use a new DynamicAll for
+// a new cycle.
+//
+// The zero value is ready to use, but a DynamicAll
+// must not be copied after first use.
+type DynamicAll[T any] struct {
+	mut   sync.Mutex
+	chans []*Chan[T]
+	done  bool
+}
+
+// Add registers c, so that Wait will not
+// complete until c has closed. It returns
+// ErrDynamicAllDone if the DynamicAll has
+// already completed, in which case c
+// is not registered.
+func (d *DynamicAll[T]) Add(c *Chan[T]) error {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	if d.done {
+		return ErrDynamicAllDone
+	}
+	d.chans = append(d.chans, c)
+	return nil
+}
+
+// Wait blocks until every Chan added so far, including
+// those added while Wait is blocked, has closed. It then
+// returns nil, and the DynamicAll is complete. If no
+// Chans have been added, Wait completes immediately.
+//
+// If ctx is done first, Wait returns ctx.Err(),
+// and the DynamicAll remains open to further Adds.
+func (d *DynamicAll[T]) Wait(ctx context.Context) error {
+	for i := 0; ; i++ {
+		d.mut.Lock()
+		if i == len(d.chans) {
+			d.done = true
+			d.mut.Unlock()
+			return nil
+		}
+		c := d.chans[i]
+		d.mut.Unlock()
+
+		select {
+		case <-c.WhenClosed():
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
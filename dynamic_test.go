@@ -0,0 +1,60 @@
+package loquet_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestDynamicAllAddMidWait(t *testing.T) {
+	var all loquet.DynamicAll[int]
+	first := loquet.NewChan[int](nil)
+	all.Add(first)
+
+	waitDone := make(chan error)
+	go func() {
+		waitDone <- all.Wait(context.Background())
+	}()
+
+	late := loquet.NewChan[int](nil)
+	if err := all.Add(late); err != nil {
+		t.Fatalf("Add before completion should succeed, got %v", err)
+	}
+	first.Close()
+
+	select {
+	case <-waitDone:
+		t.Fatalf("Wait completed before the late Chan closed")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	late.Close()
+	select {
+	case err := <-waitDone:
+		if err != nil {
+			t.Fatalf("unexpected error %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Wait never completed")
+	}
+
+	if err := all.Add(loquet.NewChan[int](nil)); err != loquet.ErrDynamicAllDone {
+		t.Fatalf("expected ErrDynamicAllDone after completion, got %v", err)
+	}
+}
+
+func TestDynamicAllWaitContext(t *testing.T) {
+	var all loquet.DynamicAll[int]
+	all.Add(loquet.NewChan[int](nil))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := all.Wait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+	if err := all.Add(loquet.NewChan[int](nil)); err != nil {
+		t.Fatalf("Add after a cancelled Wait should succeed, got %v", err)
+	}
+}
@@ -0,0 +1,42 @@
+package loquet
+
+import (
+	"unsafe"
+)
+
+// Equal reports whether f and other are in the same
+// observable state: both open or both closed, with
+// closeVals that eq considers equal. This makes
+// table-driven tests of loquet-based code cleaner.
+//
+// Equal holds both read locks at once, so that the two
+// states are compared at a single instant. To avoid
+// deadlock, the mutexes are always acquired in a
+// consistent order, lowest address first, no matter
+// which Chan is the receiver; so a.Equal(b, eq) may
+// run concurrently with b.Equal(a, eq) safely.
+//
+// eq is called while both mutexes are held, so it
+// must not call methods on either Chan. It may be
+// passed nil closeVals. Equal of a Chan with itself
+// takes the one mutex once.
+func (f *Chan[T]) Equal(other *Chan[T], eq func(a, b *T) bool) bool {
+	if f == other {
+		f.mut.RLock()
+		defer f.mut.RUnlock()
+		return eq(f.closeVal, f.closeVal)
+	}
+	first, second := f, other
+	if uintptr(unsafe.Pointer(second)) < uintptr(unsafe.Pointer(first)) {
+		first, second = second, first
+	}
+	first.mut.RLock()
+	defer first.mut.RUnlock()
+	second.mut.RLock()
+	defer second.mut.RUnlock()
+
+	if f.isClosed != other.isClosed {
+		return false
+	}
+	return eq(f.closeVal, other.closeVal)
+}
@@ -0,0 +1,54 @@
+package loquet_test
+
+import (
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func intEq(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func TestEqual(t *testing.T) {
+	one, alsoOne, two := 1, 1, 2
+	a := loquet.NewChan(&one)
+	b := loquet.NewChan(&alsoOne)
+	if !a.Equal(b, intEq) || !b.Equal(a, intEq) {
+		t.Fatalf("expected equal open Chans")
+	}
+	b.Close()
+	if a.Equal(b, intEq) {
+		t.Fatalf("open and closed Chans should differ")
+	}
+	a.CloseWith(&two)
+	if a.Equal(b, intEq) {
+		t.Fatalf("different closeVals should differ")
+	}
+	if !a.Equal(a, intEq) {
+		t.Fatalf("a Chan should equal itself")
+	}
+}
+
+func TestEqualConcurrentNoDeadlock(t *testing.T) {
+	a := loquet.NewChan[int](nil)
+	b := loquet.NewChan[int](nil)
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		go func(i int) {
+			for k := 0; k < 1000; k++ {
+				if i == 0 {
+					a.Equal(b, intEq)
+				} else {
+					b.Equal(a, intEq)
+				}
+			}
+			done <- struct{}{}
+		}(i)
+	}
+	<-done
+	<-done
+}
@@ -0,0 +1,69 @@
+package loquet
+
+// Errors
+//
+// The package reports failures with the exported
+// sentinel errors below, so that callers can test
+// for them with errors.Is. Which methods return which:
+//
+//   - ErrAlreadyClosed: a close attempted on a Chan that
+//     is already closed, by Close, CloseWith, CloseWithFunc,
+//     CloseWithSwap, CloseWithCause, CloseWithToken and
+//     CloseWithAndWait.
+//   - ErrAlreadyOpen: Reopen of a Chan that is open.
+//   - ErrClosed: an operation that needs an open (or
+//     reopenable) Chan found it closed for good, as
+//     with Reopen of a sealed Chan. ErrAlreadyClosed
+//     and ErrClosedBeforeMatch also match ErrClosed
+//     under errors.Is, so one test catches every
+//     "the Chan is closed" failure.
+//   - ErrClosedBeforeMatch: WaitUntil saw the Chan close
+//     before its predicate was satisfied, or WaitVersions
+//     before enough changes.
+//   - ErrTokenConsumed: CloseWithToken presented a
+//     CloseToken already consumed by some Chan.
+//   - ErrFrozen: a close, or Reopen, of a Chan
+//     made immutable by Freeze.
+//   - ErrDynamicAllDone: DynamicAll.Add after the
+//     DynamicAll completed.
+//
+// Two further sentinels are not returned as errors,
+// but recorded as close reasons in a CauseChain:
+// ErrAncestorClosed, and ErrKillSwitch.
+//
+// Methods waiting on a context return ctx.Err() when
+// it is done, and timed waits that give up return
+// context.DeadlineExceeded.
+
+var ErrClosed error = &chainedError{msg: "the loquet.Chan is closed."}
+
+var ErrAlreadyClosed error = &chainedError{msg: "the loquet.Chan is already closed.", parent: ErrClosed}
+
+var ErrAlreadyOpen error = &chainedError{msg: "the loquet.Chan is already open."}
+
+var ErrClosedBeforeMatch error = &chainedError{msg: "the loquet.Chan closed before its closeVal matched.", parent: ErrClosed}
+
+var ErrFrozen error = &chainedError{msg: "the loquet.Chan is frozen."}
+
+var ErrTokenConsumed error = &chainedError{msg: "the loquet.CloseToken has already been consumed."}
+
+var ErrDynamicAllDone error = &chainedError{msg: "the loquet.DynamicAll has already completed."}
+
+var ErrAncestorClosed error = &chainedError{msg: "an ancestor loquet.Chan closed."}
+
+var ErrKillSwitch error = &chainedError{msg: "the loquet.Lifetime kill switch fired."}
+
+// chainedError is a sentinel error that may
+// match a more general parent sentinel.
+type chainedError struct {
+	msg    string
+	parent error
+}
+
+func (e *chainedError) Error() string {
+	return e.msg
+}
+
+func (e *chainedError) Unwrap() error {
+	return e.parent
+}
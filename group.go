@@ -0,0 +1,93 @@
+package loquet
+
+import (
+	"slices"
+	"sync"
+)
+
+// Group packages the usual fan-out/fan-in lifecycle
+// of a set of workers: each worker gets its own member
+// Chan from Add, on which it reports completion by
+// closing it, and the coordinator waits for all of
+// them with WaitClosed. A typical graceful shutdown:
+//
+//	g := loquet.NewGroup[Message]()
+//	for i := 0; i < n; i++ {
+//	    done := g.Add()
+//	    go worker(shutdown, done) // closes done on exit.
+//	}
+//	...
+//	shutdown.Close()
+//	g.WaitClosed()
+//
+// Unlike DynamicAll, a Group makes its member Chans
+// itself, and never completes for good: more members
+// may be added after a WaitClosed returns, and
+// waited for with another WaitClosed.
+//
+// A Group may live as long as the program, with
+// workers coming and going: members that have closed
+// are dropped from time to time, as more are added,
+// so the Group holds on to only a bounded
+// multiple of its open members.
+type Group[T any] struct {
+	mut     sync.Mutex
+	members []*Chan[T]
+
+	// pruneAt is the number of members at which
+	// Add next drops those that have closed.
+	pruneAt int
+}
+
+// minPruneAt is the smallest Group that Add prunes.
+const minPruneAt = 16
+
+// NewGroup returns a new, empty Group.
+func NewGroup[T any]() *Group[T] {
+	return &Group[T]{}
+}
+
+// Add returns a new member Chan, open
+// and with a nil closeVal.
+//
+// Whenever the number of members has doubled since
+// the last pruning, Add first drops the members
+// that are closed, which keeps the cost of
+// pruning constant per Add, amortized.
+func (g *Group[T]) Add() (member *Chan[T]) {
+	member = NewChan[T](nil)
+	g.mut.Lock()
+	if len(g.members) >= max(g.pruneAt, minPruneAt) {
+		g.members = slices.DeleteFunc(g.members, (*Chan[T]).Closed)
+		g.pruneAt = 2 * len(g.members)
+	}
+	g.members = append(g.members, member)
+	g.mut.Unlock()
+	return
+}
+
+// Len returns the number of members the Group holds:
+// the open ones, and any closed ones not yet dropped.
+// It is diagnostic.
+func (g *Group[T]) Len() int {
+	g.mut.Lock()
+	defer g.mut.Unlock()
+	return len(g.members)
+}
+
+// WaitClosed blocks until every member added before the
+// call has closed. Members added during the wait are not
+// waited for. A member that is closed and then reset
+// before WaitClosed gets to it is waited for until it
+// closes again, unless Add has dropped it in the
+// meantime, as closed; so members are best left
+// alone once closed. With no members, WaitClosed
+// returns at once.
+func (g *Group[T]) WaitClosed() {
+	g.mut.Lock()
+	members := append([]*Chan[T](nil), g.members...)
+	g.mut.Unlock()
+	for _, m := range members {
+		<-m.WhenClosed()
+	}
+}
@@ -0,0 +1,58 @@
+package loquet_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestGroupWaitClosed(t *testing.T) {
+	shutdown := loquet.NewChan[struct{}](nil)
+	g := loquet.NewGroup[int]()
+	var exited atomic.Int64
+	for i := 0; i < 5; i++ {
+		done := g.Add()
+		go func() {
+			<-shutdown.WhenClosed()
+			exited.Add(1)
+			done.CloseWith(&i)
+		}()
+	}
+	shutdown.Close()
+	g.WaitClosed()
+	if n := exited.Load(); n != 5 {
+		t.Fatalf("expected all 5 workers to have exited, got %v", n)
+	}
+
+	// the Group may be reused.
+	late := g.Add()
+	late.Close()
+	g.WaitClosed()
+
+	loquet.NewGroup[int]().WaitClosed() // no members.
+}
+
+func TestGroupDropsClosedMembers(t *testing.T) {
+	g := loquet.NewGroup[int]()
+	for i := 0; i < 1000; i++ {
+		g.Add().Close()
+	}
+	open := g.Add()
+	if n := g.Len(); n > 64 {
+		t.Fatalf("expected closed members dropped, still holding %v", n)
+	}
+	done := make(chan struct{})
+	go func() {
+		g.WaitClosed()
+		close(done)
+	}()
+	select {
+	case <-done:
+		t.Fatalf("expected WaitClosed to wait for the open member")
+	case <-time.After(20 * time.Millisecond):
+	}
+	open.Close()
+	<-done
+}
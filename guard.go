@@ -0,0 +1,64 @@
+package loquet
+
+// notifyGuard issues the WhenClosed channels
+// of a Chan built WithNotifyChannelGuard.
+type notifyGuard struct {
+	// issued are the channels handed out since the last
+	// close or reset, all to be closed on the next close.
+	issued []chan struct{}
+}
+
+// issue returns a new channel for one WhenClosed call.
+func (g *notifyGuard) issue(isClosed bool) chan struct{} {
+	ch := make(chan struct{})
+	if isClosed {
+		close(ch)
+		return ch
+	}
+	g.issued = append(g.issued, ch)
+	return ch
+}
+
+// closeAll closes every channel issued in this cycle.
+func (g *notifyGuard) closeAll() {
+	for _, ch := range g.issued {
+		close(ch)
+	}
+	g.issued = nil
+}
+
+// abandon forgets every channel issued in this
+// cycle. They will never be closed.
+func (g *notifyGuard) abandon() {
+	g.issued = nil
+}
+
+// WithNotifyChannelGuard is a debugging aid that
+// enforces the rule that the channel returned by
+// WhenClosed() must be used just in time, on the
+// right hand side of a channel operation,
+// and never stored.
+//
+// With the guard, every WhenClosed() call returns a
+// distinct channel, all backed by the same underlying
+// close event. Any reset of the Chan (Reset, ReadAndReset,
+// ReadVersionAndReset) abandons all of the channels
+// issued before it, even if the Chan was open, so that
+// they never fire. Code that wrongly caches a
+// WhenClosed channel across a reset thus hangs
+// deterministically in testing, instead of
+// working by accident, while code that calls
+// WhenClosed() afresh works as usual. The exception
+// is ResetReuse of an open Chan, which promises to
+// keep the WhenClosed channel, and so keeps the
+// guarded channels issued for it as well.
+//
+// Since each call allocates a channel that is retained
+// until the next close or reset, the guard is not
+// intended for production use, especially with
+// callers that poll WhenClosed() in a loop.
+func WithNotifyChannelGuard[T any]() Option[T] {
+	return func(f *Chan[T]) {
+		f.guard = &notifyGuard{}
+	}
+}
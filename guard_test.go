@@ -0,0 +1,98 @@
+package loquet_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestNotifyChannelGuardStaleAfterReset(t *testing.T) {
+	c := loquet.NewChan[int](nil, loquet.WithNotifyChannelGuard[int]())
+
+	if c.WhenClosed() == c.WhenClosed() {
+		t.Fatalf("expected a distinct channel from each WhenClosed call")
+	}
+
+	cached := c.WhenClosed() // wrong: stored across a reset.
+	c.ReadAndReset(nil)
+	c.Close()
+
+	select {
+	case <-c.WhenClosed():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("fresh WhenClosed channel should be closed")
+	}
+	select {
+	case <-cached:
+		t.Fatalf("cached channel should have gone stale after reset")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
+
+func TestNotifyChannelGuardFires(t *testing.T) {
+	c := loquet.NewChan[int](nil, loquet.WithNotifyChannelGuard[int]())
+	a, b := c.WhenClosed(), c.WhenClosed()
+	c.Close()
+	<-a
+	<-b
+}
+
+func TestNotifyChannelGuardResetReuse(t *testing.T) {
+	c := loquet.NewChan[int](nil, loquet.WithNotifyChannelGuard[int]())
+	before := c.WhenClosed()
+	c.ResetReuse(nil) // open: the WhenClosed channel is kept.
+	c.Close()
+	select {
+	case <-before:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected a channel kept by ResetReuse to fire")
+	}
+}
+
+func TestNotifyChannelGuardMarkClosed(t *testing.T) {
+	// DebugChecks, on for the suite, checks the
+	// guard's invariant after each operation.
+	c := loquet.NewChan[int](nil, loquet.WithNotifyChannelGuard[int]())
+	before := c.WhenClosed()
+	c.MarkClosed()
+	after := c.WhenClosed()
+	select {
+	case <-after:
+		t.Fatalf("expected WhenClosed to stay open on a marked Chan")
+	default:
+	}
+	c.Close()
+	for _, ch := range []<-chan struct{}{before, after} {
+		select {
+		case <-ch:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected Close to fire every issued channel")
+		}
+	}
+}
+
+func TestNotifyChannelGuardGen(t *testing.T) {
+	c := loquet.NewChan[int](nil, loquet.WithNotifyChannelGuard[int]())
+	cached, gen := c.WhenClosedGen()
+	c.ReadAndReset(nil) // open, but the guard abandons cached.
+	if now := c.WhenClosedGeneration(); now == gen {
+		t.Fatalf("expected a new generation once cached was abandoned")
+	}
+	c.Close()
+	select {
+	case <-cached:
+		t.Fatalf("expected the abandoned channel never to fire")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// ResetReuse keeps the issued channels, and the generation.
+	c.ReadAndReset(nil)
+	kept, gen := c.WhenClosedGen()
+	c.ResetReuse(nil)
+	if now := c.WhenClosedGeneration(); now != gen {
+		t.Fatalf("expected ResetReuse of an open Chan to keep generation %v, got %v", gen, now)
+	}
+	c.Close()
+	<-kept
+}
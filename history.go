@@ -0,0 +1,97 @@
+package loquet
+
+// WithHistory makes the Chan remember the closeVals of
+// its last n versions, oldest first; see History. Each
+// operation that bumps the version (Set, CloseWith,
+// Reset, and so on) appends the new closeVal, and
+// once n are held the oldest is discarded. Plain
+// Close, which does not change the closeVal, adds
+// nothing, even when WithSequence gives it a new
+// version. A non-positive n disables the history.
+func WithHistory[T any](n int) Option[T] {
+	return func(f *Chan[T]) {
+		f.historyCap = n
+	}
+}
+
+// History returns a copy of the remembered closeVals,
+// oldest first. The initial closeVal given to NewChan
+// is not included, as it is not the product of an
+// update. It returns nil unless the Chan was created
+// WithHistory. The values record
+// the closeVals at the time they were installed, as
+// pointers; the pointed-to values are not copied.
+func (f *Chan[T]) History() (vals []*T) {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	if len(f.history) == 0 {
+		return nil
+	}
+	return append([]*T(nil), f.history...)
+}
+
+// DrainHistory returns the remembered closeVals, as
+// History does, and empties the history, in one atomic
+// step. For log shipping, this ensures that each value
+// is reported once: a concurrent Set lands either
+// in this batch or in the next one, never in both,
+// and is never lost between reading and clearing.
+// The history keeps its capacity from WithHistory.
+func (f *Chan[T]) DrainHistory() (vals []*T) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	vals = f.history
+	f.history = nil
+	return
+}
+
+// WithFullLog makes the Chan keep every closeVal it is
+// ever given, in order; see Log. Where WithHistory
+// bounds the memory used, WithFullLog puts
+// completeness first, for forensic debugging.
+//
+// The log is never trimmed, and holds a pointer to
+// every value, which the garbage collector can
+// therefore never reclaim while the Chan lives. It
+// grows by one entry per update without limit, so
+// use it only on short-lived Chans, or on those
+// updated a bounded number of times.
+func WithFullLog[T any]() Option[T] {
+	return func(f *Chan[T]) {
+		f.fullLog = true
+	}
+}
+
+// Log returns a copy of every closeVal installed since
+// NewChan, oldest first, under the same rules as
+// History: one entry per version, not including the
+// initial closeVal. It returns nil unless the
+// Chan was created WithFullLog.
+func (f *Chan[T]) Log() (vals []*T) {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	if len(f.log) == 0 {
+		return nil
+	}
+	return append([]*T(nil), f.log...)
+}
+
+// rememberLocked appends the current closeVal to the
+// history and the full log, if they are kept. It is
+// called by recordLocked for the operations that
+// changed the closeVal, as told before any Sequence
+// draws a version, so that a plain Close adds nothing
+// even when it draws one. f.mut must be held.
+func (f *Chan[T]) rememberLocked() {
+	if f.fullLog {
+		f.log = append(f.log, f.closeVal)
+	}
+	if f.historyCap <= 0 {
+		return
+	}
+	if len(f.history) == f.historyCap {
+		copy(f.history, f.history[1:])
+		f.history = f.history[:len(f.history)-1]
+	}
+	f.history = append(f.history, f.closeVal)
+}
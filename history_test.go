@@ -0,0 +1,85 @@
+package loquet_test
+
+import (
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestHistory(t *testing.T) {
+	c := loquet.NewChan[int](nil, loquet.WithHistory[int](3))
+	if h := c.History(); h != nil {
+		t.Fatalf("expected no history yet, got %v", h)
+	}
+	for i := 1; i <= 4; i++ {
+		c.Set(&i)
+	}
+	c.Close() // no new version: not recorded.
+
+	h := c.History()
+	if len(h) != 3 || *h[0] != 2 || *h[1] != 3 || *h[2] != 4 {
+		t.Fatalf("expected the last 3 values 2,3,4; got %v", h)
+	}
+
+	plain := loquet.NewChan[int](nil)
+	one := 1
+	plain.Set(&one)
+	if h := plain.History(); h != nil {
+		t.Fatalf("expected no history without WithHistory, got %v", h)
+	}
+}
+
+func TestHistoryWithSequence(t *testing.T) {
+	var seq loquet.Sequence
+	c := loquet.NewChan[int](nil,
+		loquet.WithSequence[int](&seq),
+		loquet.WithHistory[int](3),
+		loquet.WithFullLog[int](),
+	)
+	one := 1
+	c.Set(&one)
+	c.Close() // draws a version, but changes nothing.
+	if h := c.History(); len(h) != 1 {
+		t.Fatalf("expected 1 history entry, got %v", len(h))
+	}
+	if log := c.Log(); len(log) != 1 {
+		t.Fatalf("expected 1 log entry, got %v", len(log))
+	}
+}
+
+func TestFullLog(t *testing.T) {
+	c := loquet.NewChan[int](nil, loquet.WithFullLog[int]())
+	for i := 1; i <= 100; i++ {
+		c.Set(&i)
+	}
+	c.Close()
+	log := c.Log()
+	if len(log) != 100 {
+		t.Fatalf("expected all 100 values, got %v", len(log))
+	}
+	for i, v := range log {
+		if *v != i+1 {
+			t.Fatalf("expected %v at %v, got %v", i+1, i, *v)
+		}
+	}
+	if h := c.History(); h != nil {
+		t.Fatalf("WithFullLog must not enable History, got %v", h)
+	}
+}
+
+func TestDrainHistory(t *testing.T) {
+	c := loquet.NewChan[int](nil, loquet.WithHistory[int](10))
+	one, two, three := 1, 2, 3
+	c.Set(&one)
+	c.Set(&two)
+	if got := c.DrainHistory(); len(got) != 2 || *got[0] != 1 || *got[1] != 2 {
+		t.Fatalf("expected 1,2; got %v", got)
+	}
+	if got := c.DrainHistory(); got != nil {
+		t.Fatalf("expected an empty history after draining, got %v", got)
+	}
+	c.Set(&three)
+	if got := c.History(); len(got) != 1 || *got[0] != 3 {
+		t.Fatalf("expected only the new value 3, got %v", got)
+	}
+}
@@ -0,0 +1,39 @@
+package loquet
+
+import (
+	"encoding/json"
+)
+
+// chanSnapshot is the JSON form of a Chan.
+type chanSnapshot[T any] struct {
+	Closed   bool  `json:"closed"`
+	Version  int64 `json:"version"`
+	CloseVal *T    `json:"closeVal"`
+}
+
+// MarshalJSON implements json.Marshaler, emitting
+// a snapshot of the Chan's current state as
+// an object of the form
+//
+//	{"closed": false, "version": 3, "closeVal": <marshaled T>}
+//
+// The state is captured under the mutex, so the
+// fields are mutually consistent, but marshaling
+// of the closeVal itself happens after the mutex
+// is released. A nil closeVal is marshaled as null.
+//
+// This is intended for logging and debug endpoints.
+// There is deliberately no UnmarshalJSON: a Chan
+// must be created with NewChan, and restoring
+// the closed status of a live Chan from JSON
+// would be surprising at best.
+func (f *Chan[T]) MarshalJSON() ([]byte, error) {
+	f.mut.RLock()
+	snap := chanSnapshot[T]{
+		Closed:   f.isClosed,
+		Version:  f.version,
+		CloseVal: f.closeVal,
+	}
+	f.mut.RUnlock()
+	return json.Marshal(snap)
+}
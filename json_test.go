@@ -0,0 +1,29 @@
+package loquet_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestMarshalJSON(t *testing.T) {
+	c := loquet.NewChan[Config](nil)
+	by, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if string(by) != `{"closed":false,"version":0,"closeVal":null}` {
+		t.Fatalf("unexpected JSON for new Chan: %v", string(by))
+	}
+
+	c.Set(&Config{Level: 1})
+	c.CloseWith(&Config{Level: 2})
+	by, err = json.Marshal(c)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if string(by) != `{"closed":true,"version":2,"closeVal":{"Level":2}}` {
+		t.Fatalf("unexpected JSON for closed Chan: %v", string(by))
+	}
+}
@@ -0,0 +1,145 @@
+package loquet
+
+// Lifecycle is the state of a Chan, viewed as a formal
+// state machine. The allowed transitions are:
+//
+//	Open         -> Closed        (Close, CloseWith, ...)
+//	Open         -> ResetPending  (a reset of an open Chan)
+//	Closed       -> ResetPending  (a reset, or Reopen, of a closed Chan)
+//	ResetPending -> Open          (completion of any reset)
+//	Open         -> Sealed        (Seal of an open Chan)
+//	Closed       -> Sealed        (Seal of a closed Chan)
+//
+// Sealed is terminal. ResetPending is transitional: a
+// reset happens atomically under the Chan's mutex, so
+// the Lifecycle method never reports ResetPending;
+// it is visible only to transition hooks, which see
+// each reset as a pair of transitions passing through
+// it. This lets a hook tell a reset from a close.
+type Lifecycle int
+
+const (
+	LifecycleOpen Lifecycle = iota
+	LifecycleClosed
+	LifecycleResetPending
+	LifecycleSealed
+)
+
+func (s Lifecycle) String() string {
+	switch s {
+	case LifecycleOpen:
+		return "Open"
+	case LifecycleClosed:
+		return "Closed"
+	case LifecycleResetPending:
+		return "ResetPending"
+	case LifecycleSealed:
+		return "Sealed"
+	}
+	return "Unknown"
+}
+
+// WithTransitionHook arranges for hook to be called
+// on every change in the Chan's Lifecycle, with the
+// states before and after the change, so that users
+// can log or enforce the valid transitions
+// documented on Lifecycle.
+//
+// Like WithAuditLog, hooks are called in order
+// after the Chan's mutex has been released.
+func WithTransitionHook[T any](hook func(from, to Lifecycle)) Option[T] {
+	return func(f *Chan[T]) {
+		f.transitionHook = hook
+	}
+}
+
+// Lifecycle returns the current state of the Chan:
+// LifecycleOpen, LifecycleClosed or LifecycleSealed.
+func (f *Chan[T]) Lifecycle() Lifecycle {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	return f.lifecycleLocked()
+}
+
+// lifecycleLocked derives the Lifecycle. f.mut must be held.
+func (f *Chan[T]) lifecycleLocked() Lifecycle {
+	switch {
+	case f.sealed:
+		return LifecycleSealed
+	case f.isClosed:
+		return LifecycleClosed
+	}
+	return LifecycleOpen
+}
+
+// Seal makes the Chan permanently closed. If the Chan
+// is open, Seal closes it, leaving the closeVal as is,
+// just like Close. Thereafter the Chan can never be
+// reset or reopened: the reset methods leave it
+// closed, and Reopen returns ErrClosed. The closeVal can still be changed by Set.
+//
+// Seal is idempotent.
+func (f *Chan[T]) Seal() {
+	f.mut.Lock()
+	defer f.unlock()
+	if f.sealed || f.frozen {
+		return
+	}
+	f.sealed = true
+	if f.isClosed {
+		f.recordLocked(kindSeal, "Seal", f.closeVal)
+		return
+	}
+	f.closePlainLocked()
+	f.recordLocked(kindClose, "Seal", f.closeVal)
+}
+
+// Freeze makes the Chan immutable, open or closed, as it
+// stands, for handing to code that must not be able to
+// tamper with it. Freeze is irreversible. Thereafter
+// every operation that would change the Chan leaves it
+// alone: the closes (Close, CloseWith and the rest)
+// and Reopen return ErrFrozen, or report false where
+// they return a bool; the Set methods return the
+// current closeVal without replacing it; the reset
+// methods, ConsumeCloseVal and Seal do nothing.
+// Reads and waits work as before.
+//
+// Freezing an open Chan means it will never close,
+// so waiters on it must rely on a context or timeout;
+// usually one freezes a Chan after closing it. Unlike
+// Seal, which only stops the Chan from reopening,
+// Freeze also fixes the closeVal. Freeze is idempotent.
+func (f *Chan[T]) Freeze() {
+	f.mut.Lock()
+	f.frozen = true
+	f.mut.Unlock()
+}
+
+// Reopen returns a closed Chan to the open state,
+// keeping its current closeVal, unlike the reset
+// methods which replace it. After Reopen,
+// WhenClosed() returns a fresh channel that
+// is closed on the next close.
+//
+// Reopen returns ErrAlreadyOpen if the Chan is open,
+// ErrClosed if it is sealed and so can never
+// reopen, and ErrFrozen if it is frozen. A nil
+// error means the Chan was reopened.
+func (f *Chan[T]) Reopen() error {
+	f.mut.Lock()
+	defer f.unlock()
+	if f.frozen {
+		return ErrFrozen
+	}
+	if f.sealed {
+		return ErrClosed
+	}
+	if !f.isClosed {
+		return ErrAlreadyOpen
+	}
+	f.reopenLocked()
+	f.version++
+	f.recordLocked(kindReset, "Reopen", f.closeVal)
+	return nil
+}
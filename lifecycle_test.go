@@ -0,0 +1,109 @@
+package loquet_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestTransitionHook(t *testing.T) {
+	var got []string
+	c := loquet.NewChan[int](nil,
+		loquet.WithTransitionHook[int](func(from, to loquet.Lifecycle) {
+			got = append(got, fmt.Sprintf("%v->%v", from, to))
+		}))
+
+	c.Set(nil) // no transition.
+	c.Close()
+	c.ReadAndReset(nil)
+	c.Seal()
+	c.ReadAndReset(nil) // refused on a sealed Chan.
+
+	want := []string{
+		"Open->Closed",
+		"Closed->ResetPending",
+		"ResetPending->Open",
+		"Open->Sealed",
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected transitions %v, got %v", want, got)
+	}
+	if c.Lifecycle() != loquet.LifecycleSealed {
+		t.Fatalf("expected Sealed, got %v", c.Lifecycle())
+	}
+	if _, isClosed := c.Read(); !isClosed {
+		t.Fatalf("a sealed Chan must remain closed")
+	}
+}
+
+func TestSealClosedChan(t *testing.T) {
+	var got []string
+	c := loquet.NewChan[int](nil,
+		loquet.WithTransitionHook[int](func(from, to loquet.Lifecycle) {
+			got = append(got, fmt.Sprintf("%v->%v", from, to))
+		}))
+	c.Close()
+	c.Seal()
+	c.Seal()
+	if fmt.Sprint(got) != "[Open->Closed Closed->Sealed]" {
+		t.Fatalf("unexpected transitions %v", got)
+	}
+}
+
+func TestReopenSentinels(t *testing.T) {
+	one := 1
+	c := loquet.NewChan(&one)
+	if err := c.Reopen(); err != loquet.ErrAlreadyOpen {
+		t.Fatalf("expected ErrAlreadyOpen, got %v", err)
+	}
+	c.Close()
+	if err := c.Close(); !errors.Is(err, loquet.ErrClosed) {
+		t.Fatalf("expected ErrAlreadyClosed to match ErrClosed, got %v", err)
+	}
+	if err := c.Reopen(); err != nil {
+		t.Fatalf("expected Reopen of a closed Chan to succeed, got %v", err)
+	}
+	val, isClosed := c.Read()
+	if isClosed || val != &one {
+		t.Fatalf("expected open with the closeVal kept")
+	}
+	c.Seal()
+	if err := c.Reopen(); err != loquet.ErrClosed {
+		t.Fatalf("expected ErrClosed reopening a sealed Chan, got %v", err)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	one, two := 1, 2
+	c := loquet.NewChan[int](&one)
+	c.CloseWith(&one)
+	c.Freeze()
+	c.Freeze() // idempotent.
+
+	c.Set(&two)
+	c.SetAndClose(&two)
+	c.Reset(&two)
+	c.ReadAndReset(&two)
+	c.ConsumeCloseVal()
+	if err := c.Reopen(); err != loquet.ErrFrozen {
+		t.Fatalf("expected ErrFrozen from Reopen, got %v", err)
+	}
+	if val, isClosed := c.Read(); val != &one || !isClosed {
+		t.Fatalf("expected the frozen Chan unchanged")
+	}
+
+	// an open Chan can be frozen too.
+	o := loquet.NewChan[int](&one)
+	o.Freeze()
+	if err := o.CloseWith(&two); err != loquet.ErrFrozen {
+		t.Fatalf("expected ErrFrozen from CloseWith, got %v", err)
+	}
+	if old := o.SetIfOpen(&two); old != &one {
+		t.Fatalf("expected SetIfOpen to return the current closeVal")
+	}
+	if val, isClosed := o.Read(); val != &one || isClosed {
+		t.Fatalf("expected the frozen Chan unchanged")
+	}
+}
@@ -0,0 +1,161 @@
+package loquet
+
+import (
+	"context"
+	"time"
+)
+
+// LifetimeCause identifies which source of a
+// Lifetime closed a Chan.
+type LifetimeCause int
+
+const (
+	// LifetimeCauseNone means no Lifetime source has
+	// closed the Chan: it is open, or it was closed
+	// by other means, such as a direct Close.
+	LifetimeCauseNone LifetimeCause = iota
+	LifetimeCauseContext
+	LifetimeCauseDeadline
+	LifetimeCauseKillSwitch
+)
+
+func (c LifetimeCause) String() string {
+	switch c {
+	case LifetimeCauseNone:
+		return "None"
+	case LifetimeCauseContext:
+		return "Context"
+	case LifetimeCauseDeadline:
+		return "Deadline"
+	case LifetimeCauseKillSwitch:
+		return "KillSwitch"
+	}
+	return "Unknown"
+}
+
+// LifetimeBuilder accumulates the cancellation sources
+// of a Chan; see Lifetime.
+type LifetimeBuilder[T any] struct {
+	ctx        context.Context
+	deadline   time.Time
+	killSwitch <-chan struct{}
+	opts       []Option[T]
+}
+
+// Lifetime starts building a Chan bound to several
+// cancellation sources at once, with first-wins
+// semantics. It consolidates the family of binding
+// helpers (BindAfterFunc, CloseAt, ...) into one
+// composable builder:
+//
+//	c := loquet.Lifetime[Message]().
+//	    Context(ctx).
+//	    Deadline(time.Now().Add(time.Minute)).
+//	    KillSwitch(shutdownCh).
+//	    Build(&Message{Err: errStopped})
+//
+// The built Chan closes with the given closeVal on
+// whichever source fires first, and records that
+// source, which is then available from CloseCause.
+// Sources that are not set never fire.
+func Lifetime[T any]() *LifetimeBuilder[T] {
+	return &LifetimeBuilder[T]{}
+}
+
+// Context makes the Chan close when ctx is done.
+func (b *LifetimeBuilder[T]) Context(ctx context.Context) *LifetimeBuilder[T] {
+	b.ctx = ctx
+	return b
+}
+
+// Deadline makes the Chan close at time t.
+func (b *LifetimeBuilder[T]) Deadline(t time.Time) *LifetimeBuilder[T] {
+	b.deadline = t
+	return b
+}
+
+// KillSwitch makes the Chan close when ch
+// is closed or receives a value.
+func (b *LifetimeBuilder[T]) KillSwitch(ch <-chan struct{}) *LifetimeBuilder[T] {
+	b.killSwitch = ch
+	return b
+}
+
+// With adds options for NewChan to the built Chan,
+// such as WithClock, on which the Deadline is then
+// measured, or WithName.
+func (b *LifetimeBuilder[T]) With(opts ...Option[T]) *LifetimeBuilder[T] {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+// Build returns a new, open Chan that will close with
+// closeVal when the first of the sources fires. A single
+// watcher goroutine serves all of the sources, and exits
+// as soon as the Chan is closed, whether by one of the
+// sources or by any other means.
+//
+// The cause chain (see CauseChain) of a Lifetime close
+// is context.Cause(ctx) for the Context source,
+// context.DeadlineExceeded for the Deadline,
+// and ErrKillSwitch for the KillSwitch. The
+// Deadline is measured on the Chan's Clock.
+func (b *LifetimeBuilder[T]) Build(closeVal *T) (f *Chan[T]) {
+	f = NewChan[T](nil, b.opts...)
+
+	var ctxDone <-chan struct{}
+	if b.ctx != nil {
+		ctxDone = b.ctx.Done()
+	}
+	var deadline <-chan time.Time
+	if !b.deadline.IsZero() {
+		clock := f.getClock()
+		deadline = clock.After(b.deadline.Sub(clock.Now()))
+	}
+	ctx := b.ctx
+	killSwitch := b.killSwitch
+	whenClosed := f.WhenClosed()
+
+	spawn(func() {
+		select {
+		case <-ctxDone:
+			f.closeForLifetime(closeVal, LifetimeCauseContext, context.Cause(ctx))
+		case <-deadline:
+			f.closeForLifetime(closeVal, LifetimeCauseDeadline, context.DeadlineExceeded)
+		case <-killSwitch:
+			f.closeForLifetime(closeVal, LifetimeCauseKillSwitch, ErrKillSwitch)
+		case <-whenClosed:
+		}
+	})
+	return
+}
+
+// closeForLifetime closes the Chan with closeVal on behalf
+// of a Lifetime source, recording cause and reason.
+func (f *Chan[T]) closeForLifetime(closeVal *T, cause LifetimeCause, reason error) {
+	f.mut.Lock()
+	defer f.unlock()
+	if f.frozen {
+		return
+	}
+	if f.isClosed {
+		return
+	}
+	old := f.closeVal
+	f.closeVal = closeVal
+	f.causes = []error{reason}
+	f.lifetimeCause = cause
+	f.version++
+	f.closeLocked()
+	f.recordLocked(kindClose, "Lifetime", old)
+}
+
+// CloseCause reports which source of a Lifetime
+// closed the Chan, or LifetimeCauseNone if no
+// Lifetime source has closed it. A reset
+// clears the recorded cause.
+func (f *Chan[T]) CloseCause() LifetimeCause {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	return f.lifetimeCause
+}
@@ -0,0 +1,91 @@
+package loquet_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func waitClosed[T any](t *testing.T, c *loquet.Chan[T]) {
+	t.Helper()
+	select {
+	case <-c.WhenClosed():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Chan never closed")
+	}
+}
+
+func TestLifetimeContextWins(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	c := loquet.Lifetime[Message]().
+		Context(ctx).
+		Deadline(time.Now().Add(time.Hour)).
+		KillSwitch(make(chan struct{})).
+		Build(&Message{})
+	cancel()
+	waitClosed(t, c)
+	if cause := c.CloseCause(); cause != loquet.LifetimeCauseContext {
+		t.Fatalf("expected Context cause, got %v", cause)
+	}
+	if chain := c.CauseChain(); len(chain) != 1 || chain[0] != context.Canceled {
+		t.Fatalf("expected context.Canceled reason, got %v", chain)
+	}
+}
+
+func TestLifetimeDeadlineWins(t *testing.T) {
+	c := loquet.Lifetime[Message]().
+		Context(context.Background()).
+		Deadline(time.Now().Add(10 * time.Millisecond)).
+		Build(&Message{})
+	waitClosed(t, c)
+	if cause := c.CloseCause(); cause != loquet.LifetimeCauseDeadline {
+		t.Fatalf("expected Deadline cause, got %v", cause)
+	}
+}
+
+func TestLifetimeDeadlineFakeClock(t *testing.T) {
+	clock := newFakeClock()
+	c := loquet.Lifetime[Message]().
+		Deadline(clock.Now().Add(time.Hour)).
+		With(loquet.WithClock[Message](clock)).
+		Build(&Message{})
+
+	clock.Advance(59 * time.Minute)
+	if c.Closed() {
+		t.Fatalf("closed before the deadline")
+	}
+	clock.Advance(time.Minute)
+	waitClosed(t, c)
+	if cause := c.CloseCause(); cause != loquet.LifetimeCauseDeadline {
+		t.Fatalf("expected Deadline cause, got %v", cause)
+	}
+}
+
+func TestLifetimeKillSwitchWins(t *testing.T) {
+	kill := make(chan struct{})
+	c := loquet.Lifetime[Message]().
+		Deadline(time.Now().Add(time.Hour)).
+		KillSwitch(kill).
+		Build(&Message{})
+	close(kill)
+	waitClosed(t, c)
+	if cause := c.CloseCause(); cause != loquet.LifetimeCauseKillSwitch {
+		t.Fatalf("expected KillSwitch cause, got %v", cause)
+	}
+	if chain := c.CauseChain(); !errors.Is(chain[0], loquet.ErrKillSwitch) {
+		t.Fatalf("expected ErrKillSwitch reason, got %v", chain)
+	}
+}
+
+func TestLifetimeDirectClose(t *testing.T) {
+	c := loquet.Lifetime[Message]().
+		Deadline(time.Now().Add(time.Hour)).
+		Build(&Message{})
+	c.Close()
+	if cause := c.CloseCause(); cause != loquet.LifetimeCauseNone {
+		t.Fatalf("expected no Lifetime cause for a direct Close, got %v", cause)
+	}
+}
@@ -1,12 +1,11 @@
 package loquet
 
 import (
-	"fmt"
-	"sync"
+	"slices"
+	"sync/atomic"
+	"time"
 )
 
-var ErrAlreadyClosed = fmt.Errorf("the loquet.Chan is already closed.")
-
 // Chan encapsulates in one convenient
 // place several common patterns that
 // Go developers often find need of.
@@ -81,7 +80,7 @@ var ErrAlreadyClosed = fmt.Errorf("the loquet.Chan is already closed.")
 //
 // A call to NewChan() is required to produce a new Chan.
 // Although the zero-value of a Chan is currently viable,
-// it contains a sync.Mutex, and so cannot be
+// it contains a sync.RWMutex, and so cannot be
 // copied after first use anyway. Moreover we want to
 // preserve our ability to change this in the future;
 // to make the zero-value not viable if it improves
@@ -91,22 +90,202 @@ var ErrAlreadyClosed = fmt.Errorf("the loquet.Chan is already closed.")
 // state correct, user code should always deal with
 // *Chan pointers. Passing a Chan by value instead
 // of by a *Chan pointer will result in incorrect,
-// undefined behavior.
+// undefined behavior. The go vet copylocks check
+// reports such copies.
 //
 // Notice that the generic parameter is a T in Chan[T], but
 // all operations deal in *T. For example, to work
 // with a closeVal of type *Message,
 // simply call NewChan[Message](closeVal *Message).
 type Chan[T any] struct {
-	mut sync.Mutex
+	// noCopy makes go vet report any copy of a
+	// Chan by value, independently of how mut
+	// happens to be implemented.
+	noCopy noCopy
+
+	// mut is taken for reading by the read-only methods,
+	// so that heavy polling with Read does not serialize.
+	// It is a sync.RWMutex, save for DeadlockTimeout.
+	mut rwMutex
 
 	whenClosed chan struct{}
 
+	// gen counts the replacements of
+	// whenClosed; see WhenClosedGen.
+	gen int64
+
 	// closeVal and isClosed are the values that
 	// we report from Read().
 	closeVal *T
 	isClosed bool
 	version  int64
+
+	// closed mirrors isClosed, for the lock-free
+	// Closed(). It is only stored while mut is
+	// held for writing, alongside isClosed.
+	closed atomic.Bool
+
+	// marked is true while isClosed was set by
+	// MarkClosed, and whenClosed is still open.
+	marked bool
+
+	// openedAt is when the Chan was made, or last
+	// re-opened; closedAt is when the current close
+	// happened. See OpenDuration and ClosedAt.
+	openedAt time.Time
+	closedAt time.Time
+
+	// viaCloseWith is true if the current close
+	// supplied the closeVal; see ClosedViaCloseWith.
+	viaCloseWith bool
+
+	// changed, if not nil, is closed and cleared on the
+	// next mutation, waking anyone waiting for a change.
+	// It is allocated on demand by readAndWatch().
+	changed chan struct{}
+
+	// causes is the cause chain of the current
+	// close, leaf first; see CauseChain.
+	causes []error
+
+	// lifetimeCause records which source of a
+	// Lifetime closed the Chan; see CloseCause.
+	lifetimeCause LifetimeCause
+
+	// guard, if not nil, issues a distinct WhenClosed
+	// channel per call; see WithNotifyChannelGuard.
+	guard *notifyGuard
+
+	// subs are the live subscribers from Subscribe().
+	subs map[*subscriber[T]]struct{}
+
+	// bsubs are the live subscribers from
+	// SubscribeBuffered(), in registration order.
+	bsubs    []*bufferedSub[T]
+	overflow OverflowPolicy
+
+	// matchers are the pending registrations
+	// from WhenMatches().
+	matchers []*matcher[T]
+
+	// targets: see WithTargets.
+	targets      []chan<- *T
+	targetPolicy OverflowPolicy
+
+	// mirrors are the Chans from Mirror()
+	// awaiting the next close.
+	mirrors []*Chan[T]
+
+	// observed is true once a reader has seen the
+	// current close; observedCh, if allocated, is
+	// closed at the same time. See CloseWithAndWait.
+	// observed is atomic so that readers holding
+	// only the read lock can set it; it is only
+	// cleared, and observedCh only assigned, under
+	// the write lock.
+	observed   atomic.Bool
+	observedCh chan struct{}
+
+	// sealed is true once Seal has been called.
+	sealed bool
+
+	// frozen is true once Freeze has been called.
+	frozen bool
+
+	// count is the counter of Add and Done.
+	count int
+
+	// lifecycle is the state of the Chan as of the last
+	// recorded event; transitionHook observes changes to it.
+	lifecycle      Lifecycle
+	transitionHook func(from, to Lifecycle)
+
+	// callbacks counts the pending callback
+	// goroutines; see WaiterCount.
+	callbacks int
+
+	// waiting counts the goroutines inside the
+	// blocking methods; see HasWaiters.
+	waiting atomic.Int64
+
+	// derivers counts the derivation waiters watching
+	// this Chan; propagated, if allocated, is closed
+	// when that count drops to zero.
+	// See WaitClosePropagated.
+	derivers   int
+	propagated chan struct{}
+
+	// everRead is true once any reader has looked at
+	// the Chan; onFirstRead holds the funcs to run
+	// at that moment. See OnFirstRead. everRead is
+	// atomic so that readers can check it before
+	// taking any lock; it is only set under the
+	// write lock.
+	everRead    atomic.Bool
+	onFirstRead []func()
+
+	// finalPriority: see WithFinalValuePriority.
+	finalPriority bool
+
+	// immutableAfterClose: see WithImmutableAfterClose.
+	immutableAfterClose bool
+
+	// coalesce is the interval from WithCoalesce.
+	// lastNotify is when waiters were last woken;
+	// held is true while a Set has not yet been
+	// notified, and flushing while a goroutine is
+	// waiting to notify it.
+	coalesce   time.Duration
+	lastNotify time.Time
+	held       bool
+	flushing   bool
+
+	// updates counts the recorded operations that changed
+	// the closeVal, that is, those that advance the
+	// version in the absence of a Sequence; see
+	// WaitVersions. recordedVersion is the version as of
+	// the last recordLocked, by which they are told apart.
+	updates         int64
+	recordedVersion int64
+
+	// checkedVersion is the version seen by the
+	// last checkLocked, when DebugChecks is on.
+	// It is atomic since checkLocked also runs
+	// under the read lock.
+	checkedVersion atomic.Int64
+
+	// name identifies the Chan in audit entries.
+	name string
+
+	clock Clock
+
+	// seq, if set, supplies the versions;
+	// see WithSequence.
+	seq *Sequence
+
+	auditLog func(AuditEntry[T])
+	logger   func(event string, version int64)
+	span     *spanHook[T]
+
+	// onClose are the callbacks from WithOnClose.
+	onClose []func(closeVal *T)
+
+	// history holds the closeVals of the last historyCap
+	// versions. See WithHistory.
+	history    []*T
+	historyCap int
+
+	// log, kept if fullLog, holds every closeVal;
+	// see WithFullLog.
+	log     []*T
+	fullLog bool
+
+	// pending holds the events recorded under mut
+	// that are yet to be delivered by unlock().
+	// emitting is true while some goroutine
+	// is delivering them.
+	pending  []event[T]
+	emitting bool
 }
 
 // WhenClosed returns a channel that
@@ -138,22 +317,73 @@ type Chan[T any] struct {
 //
 // ~~~
 func (f *Chan[T]) WhenClosed() <-chan struct{} {
-	f.mut.Lock()
-	defer f.mut.Unlock()
+	if f.guard != nil {
+		f.mut.Lock()
+		defer f.mut.Unlock()
+		return f.guard.issue(f.isClosed && !f.marked)
+	}
+	f.mut.RLock()
+	defer f.mut.RUnlock()
 	return f.whenClosed
 }
 
+// WhenClosedGen returns the current WhenClosed channel
+// together with its generation number, gen. The generation
+// starts at 0 and is incremented each time a reset
+// (Reset, Reopen, ReadAndReset and so on) replaces
+// the WhenClosed channel with a fresh one.
+//
+// This is an escape hatch for high-performance callers
+// that must cache the channel, rather than call WhenClosed
+// in every select. Such a caller should keep gen alongside
+// the cached channel, and compare it with the
+// generation from a later WhenClosedGen (or
+// WhenClosedGeneration) call: a different
+// generation means the cached channel was replaced,
+// and will never be closed by any later Close.
+// This holds WithNotifyChannelGuard as well: a reset
+// that abandons the guarded channels of an open Chan
+// starts a new generation.
+// Everyone else should keep calling WhenClosed
+// just in time, as it advises.
+func (f *Chan[T]) WhenClosedGen() (ch <-chan struct{}, gen int64) {
+	if f.guard != nil {
+		f.mut.Lock()
+		defer f.mut.Unlock()
+		return f.guard.issue(f.isClosed && !f.marked), f.gen
+	}
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	return f.whenClosed, f.gen
+}
+
+// WhenClosedGeneration returns just the current
+// generation number of the WhenClosed channel;
+// see WhenClosedGen.
+func (f *Chan[T]) WhenClosedGeneration() (gen int64) {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	return f.gen
+}
+
 // NewChan creates a new Chan, given a type T.
 // Notice that the generic parameter is a T in Chan[T], but
 // all operations deal in *T. For example, if you have
 // `var closeVal *Message = &Message{}`, then
 // simply call `NewChan[Message](closeVal)`.
-func NewChan[T any](closeVal *T) (f *Chan[T]) {
+//
+// Any opts are applied to the new Chan
+// before it is returned.
+func NewChan[T any](closeVal *T, opts ...Option[T]) (f *Chan[T]) {
 	f = &Chan[T]{
-		mut:        sync.Mutex{},
-		whenClosed: make(chan struct{}),
-		closeVal:   closeVal,
+		whenClosed:   make(chan struct{}),
+		closeVal:     closeVal,
+		targetPolicy: OverflowDropNewest,
 	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	f.openedAt = f.getClock().Now()
 	return
 }
 
@@ -181,15 +411,119 @@ func NewChan[T any](closeVal *T) (f *Chan[T]) {
 // stored internally and broadcast.
 func (f *Chan[T]) CloseWith(closeVal *T) error {
 	f.mut.Lock()
-	defer f.mut.Unlock()
+	defer f.unlock()
 
+	if f.frozen {
+		return ErrFrozen
+	}
 	if f.isClosed {
 		return ErrAlreadyClosed
 	}
-	f.isClosed = true
+	old := f.closeVal
 	f.closeVal = closeVal
 	f.version++
-	close(f.whenClosed)
+	f.closeLocked()
+	f.recordLocked(kindClose, "CloseWith", old)
+	return nil
+}
+
+// TryCloseWith is like CloseWith, but reports the
+// outcome as a boolean: won is true only for the
+// single caller that actually performed the close,
+// and false for all of the losers, whose closeVal
+// is ignored. This reads more naturally than
+// errors.Is(err, ErrAlreadyClosed) in leader-election
+// style races, where many goroutines attempt a close
+// and the winner alone proceeds.
+func (f *Chan[T]) TryCloseWith(closeVal *T) (won bool) {
+	return f.CloseWith(closeVal) == nil
+}
+
+// CloseWithSwap is like CloseWith, but also returns the
+// closeVal that was in place just before, such as an
+// intermediate value from Set. Reading it with a
+// separate Read before closing would race with
+// other updates.
+//
+// On a Chan that is already closed, nothing is changed:
+// old is the current closeVal, and err is ErrAlreadyClosed.
+func (f *Chan[T]) CloseWithSwap(closeVal *T) (old *T, err error) {
+	f.mut.Lock()
+	defer f.unlock()
+
+	if f.frozen {
+		return f.closeVal, ErrFrozen
+	}
+	old = f.closeVal
+	if f.isClosed {
+		return old, ErrAlreadyClosed
+	}
+	f.closeVal = closeVal
+	f.version++
+	f.closeLocked()
+	f.recordLocked(kindClose, "CloseWithSwap", old)
+	return
+}
+
+// SetAndClose makes closeVal the final word, no matter
+// what came before. On an open Chan, it stores closeVal
+// and closes the Chan atomically, just like CloseWith.
+// On a closed Chan, it updates the closeVal in place,
+// like Set, rather than ignoring it as CloseWith does.
+// Either way the latest SetAndClose value wins, and
+// there is no error to check.
+//
+// As with Set on a closed Chan, readers that
+// already read the earlier closeVal will not
+// necessarily see the update.
+func (f *Chan[T]) SetAndClose(closeVal *T) {
+	f.mut.Lock()
+	defer f.unlock()
+
+	if f.frozen {
+		return
+	}
+	old := f.closeVal
+	f.closeVal = closeVal
+	f.version++
+	if f.isClosed {
+		f.recordLocked(kindSet, "SetAndClose", old)
+		return
+	}
+	f.closeLocked()
+	f.recordLocked(kindClose, "SetAndClose", old)
+}
+
+// CloseWithFunc is like CloseWith, but computes
+// the new closeVal under the Chan's mutex by
+// calling fn with the current closeVal. Whatever
+// fn returns is stored as the closeVal and broadcast.
+// This allows an atomic read-modify-close, which
+// cannot be done safely with a Read followed
+// by a CloseWith, since another writer could
+// slip in between the two calls.
+//
+// If the Chan is already closed, CloseWithFunc
+// returns ErrAlreadyClosed without calling fn.
+//
+// Since fn is called while the mutex is held,
+// fn must not call any methods on this Chan,
+// or it will deadlock.
+func (f *Chan[T]) CloseWithFunc(fn func(cur *T) *T) error {
+	f.mut.Lock()
+	defer f.unlock()
+
+	if f.frozen {
+		return ErrFrozen
+	}
+	if f.isClosed {
+		return ErrAlreadyClosed
+	}
+	old := f.closeVal
+	f.closeVal = fn(old)
+	f.version++
+	f.closeLocked()
+	f.recordLocked(kindClose, "CloseWithFunc", old)
 	return nil
 }
 
@@ -210,18 +544,157 @@ func (f *Chan[T]) CloseWith(closeVal *T) error {
 // a nil error means that the WhenClosed
 // channel was closed and the internal closeVal
 // will be broadcast to Read() callers.
+//
+// After MarkClosed, Close completes the deferred
+// broadcast: it closes WhenClosed and notifies
+// subscribers, returning a nil error.
 func (f *Chan[T]) Close() error {
 	f.mut.Lock()
-	defer f.mut.Unlock()
+	defer f.unlock()
 
-	if f.isClosed {
+	if f.frozen {
+		return ErrFrozen
+	}
+	if f.isClosed && !f.marked {
 		return ErrAlreadyClosed
 	}
-	f.isClosed = true
-	close(f.whenClosed)
+	f.closePlainLocked()
+	f.recordLocked(kindClose, "Close", f.closeVal)
 	return nil
 }
 
+// CloseWithIfVersion is a compare-and-swap style close:
+// it closes the Chan with closeVal, as CloseWith does,
+// but only if the version is still expected, as last
+// observed with ReadState (or ReadSince, or Version).
+// It reports whether it closed the Chan; ok is false
+// if the Chan has been updated since, or is already
+// closed. This implements "close unless somebody else
+// changed it since I looked" without an external lock.
+//
+// Note that a plain Close does not advance the version;
+// ok is nonetheless false once the Chan is closed.
+func (f *Chan[T]) CloseWithIfVersion(expected int64, closeVal *T) (ok bool) {
+	f.mut.Lock()
+	defer f.unlock()
+
+	if f.frozen {
+		return false
+	}
+	if f.isClosed || f.version != expected {
+		return false
+	}
+	old := f.closeVal
+	f.closeVal = closeVal
+	f.version++
+	f.closeLocked()
+	f.recordLocked(kindClose, "CloseWithIfVersion", old)
+	return true
+}
+
+// CloseIf closes the Chan, keeping its current closeVal
+// as Close does, but only if pred(cur) returns true for
+// that closeVal. It reports whether it closed the Chan.
+// This gates a close on having reached a valid
+// terminal value, in state machines where an
+// intermediate value must never be broadcast as final.
+//
+// pred is evaluated under the mutex, so that no
+// update can slip in between the check and the
+// close; it must therefore be quick, and must not
+// call methods on the Chan. On a Chan that is
+// already closed, pred is not called, and
+// closed is false.
+func (f *Chan[T]) CloseIf(pred func(cur *T) bool) (closed bool) {
+	f.mut.Lock()
+	defer f.unlock()
+
+	if f.frozen {
+		return false
+	}
+	if f.isClosed && !f.marked {
+		return false
+	}
+	if !pred(f.closeVal) {
+		return false
+	}
+	f.closePlainLocked()
+	f.recordLocked(kindClose, "CloseIf", f.closeVal)
+	return true
+}
+
+// TryClose is like Close, but never waits for the
+// Chan's mutex: if the mutex is held, by a writer or
+// by any reader, TryClose returns at once with acquired
+// false, and the Chan is left alone. Otherwise acquired
+// is true, and closed reports whether this call did
+// the closing, false meaning that the Chan was
+// already closed.
+//
+// This is for latency-critical code that would rather
+// retry later than block. Heed the advice on
+// sync.Mutex.TryLock: correct uses are rare, and a
+// retry loop around TryClose is no better than Close.
+// Note too that, once it has closed the Chan, TryClose
+// delivers to observers (see WithOnClose and
+// SubscribeBuffered) like any close, and may
+// block on them.
+func (f *Chan[T]) TryClose() (closed bool, acquired bool) {
+	if !f.mut.TryLock() {
+		return false, false
+	}
+	defer f.unlock()
+
+	if f.frozen {
+		return false, true
+	}
+	if f.isClosed && !f.marked {
+		return false, true
+	}
+	f.closePlainLocked()
+	f.recordLocked(kindClose, "TryClose", f.closeVal)
+	return true, true
+}
+
+// MarkClosed sets the isClosed flag reported by Read,
+// but does NOT close the WhenClosed channel nor notify
+// subscribers. It returns was, which is true if
+// the Chan was already closed (or marked closed),
+// in which case nothing is changed.
+//
+// This is an advanced tool, for state machines that
+// want "closed" to be a logical flag for a transitional
+// period before it is broadcast. The hazard is
+// that goroutines waiting on WhenClosed() will
+// NOT wake: only readers that poll with Read, ReadSince
+// and the like will see the Chan as closed.
+// Other than Close, every operation treats a marked Chan
+// as closed; in particular, CloseWith returns
+// ErrAlreadyClosed. To end the transitional period,
+// either call Close, which completes the broadcast,
+// or reset the Chan, which re-opens it, leaving the
+// WhenClosed channel un-closed for the next close.
+func (f *Chan[T]) MarkClosed() (was bool) {
+	f.mut.Lock()
+	defer f.unlock()
+
+	if f.frozen {
+		return f.isClosed
+	}
+	if f.isClosed {
+		return true
+	}
+	f.isClosed = true
+	f.closed.Store(true)
+	f.marked = true
+	f.viaCloseWith = false
+	f.closedAt = f.getClock().Now()
+	f.observed.Store(false)
+	f.observedCh = nil
+	f.recordLocked(kindClose, "MarkClosed", f.closeVal)
+	return false
+}
+
 // Set changes the closeVal without
 // actually closing the Chan (compare to Close).
 // That is, Set will change the closeVal no
@@ -231,13 +704,44 @@ func (f *Chan[T]) Close() error {
 // but this may commonly be ignored.
 //
 // Use SetIfOpen to set a new closeVal only
-// if the Chan is still open.
+// if the Chan is still open. On a Chan made
+// WithImmutableAfterClose, Set is SetIfOpen.
 func (f *Chan[T]) Set(closeVal *T) (old *T) {
 	f.mut.Lock()
-	defer f.mut.Unlock()
+	defer f.unlock()
+	if f.frozen {
+		return f.closeVal
+	}
 	old = f.closeVal
+	if f.isClosed && f.immutableAfterClose {
+		return
+	}
 	f.closeVal = closeVal
 	f.version++
+	f.recordLocked(kindSet, "Set", old)
+	return
+}
+
+// Swap stores new as the closeVal and returns the
+// previous closeVal in old, following the naming
+// of the Swap methods in sync/atomic.
+//
+// Swap always replaces the closeVal and bumps the
+// version, whether the Chan is open or closed, and
+// never changes the open/closed status. It is
+// equivalent to Set; the distinct name is for
+// readers who want the unconditional exchange
+// to be explicit at the call site.
+func (f *Chan[T]) Swap(new *T) (old *T) {
+	f.mut.Lock()
+	defer f.unlock()
+	if f.frozen {
+		return f.closeVal
+	}
+	old = f.closeVal
+	f.closeVal = new
+	f.version++
+	f.recordLocked(kindSet, "Swap", old)
 	return
 }
 
@@ -249,13 +753,73 @@ func (f *Chan[T]) Set(closeVal *T) (old *T) {
 // being closed.
 func (f *Chan[T]) SetIfOpen(closeVal *T) (old *T) {
 	f.mut.Lock()
-	defer f.mut.Unlock()
+	defer f.unlock()
+	if f.frozen {
+		return f.closeVal
+	}
 	old = f.closeVal
 	if f.isClosed {
 		return
 	}
 	f.closeVal = closeVal
 	f.version++
+	f.recordLocked(kindSet, "SetIfOpen", old)
+	return
+}
+
+// SetFunc atomically updates the closeVal based on
+// its current value, without closing the Chan.
+// Under the mutex, fn is called with the current
+// closeVal and its result is stored as the new
+// closeVal. This prevents the classic race where
+// another writer slips in between a Read() and
+// a subsequent Set().
+//
+// The previous closeVal is returned in old.
+//
+// Like Set, SetFunc updates the closeVal whether
+// the Chan is open or closed. Use SetFuncIfOpen to
+// only update an open Chan. On a Chan made
+// WithImmutableAfterClose, SetFunc is SetFuncIfOpen.
+//
+// Since fn is called while the mutex is held,
+// fn must not call any methods on this Chan,
+// or it will deadlock.
+func (f *Chan[T]) SetFunc(fn func(cur *T) *T) (old *T) {
+	f.mut.Lock()
+	defer f.unlock()
+	if f.frozen {
+		return f.closeVal
+	}
+	old = f.closeVal
+	if f.isClosed && f.immutableAfterClose {
+		return
+	}
+	f.closeVal = fn(old)
+	f.version++
+	f.recordLocked(kindSet, "SetFunc", old)
+	return
+}
+
+// SetFuncIfOpen is a no-op if the Chan is closed,
+// in which case fn is not called.
+// Otherwise, it behaves like SetFunc().
+// SetFuncIfOpen always returns the current
+// internal closeVal in old, even if it was
+// not updated due to the Chan being closed.
+func (f *Chan[T]) SetFuncIfOpen(fn func(cur *T) *T) (old *T) {
+	f.mut.Lock()
+	defer f.unlock()
+	if f.frozen {
+		return f.closeVal
+	}
+	old = f.closeVal
+	if f.isClosed {
+		return
+	}
+	f.closeVal = fn(old)
+	f.version++
+	f.recordLocked(kindSet, "SetFuncIfOpen", old)
 	return
 }
 
@@ -290,10 +854,191 @@ func (f *Chan[T]) SetIfOpen(closeVal *T) (old *T) {
 ~~~
 */
 func (f *Chan[T]) Read() (closeVal *T, isClosed bool) {
-	f.mut.Lock()
+	f.rlockForRead()
+	if DebugChecks {
+		f.checkLocked("Read")
+	}
 	closeVal = f.closeVal
 	isClosed = f.isClosed
-	f.mut.Unlock()
+	f.runlockForRead()
+	return
+}
+
+// ReadSince supports efficient poll loops that only act
+// when something changed. It returns the current closeVal,
+// isClosed status, and version, along with changed,
+// which is true only if version is greater than
+// lastVersion. Feed the returned version back in as
+// lastVersion on the next poll. Unlike
+// ReadVersionAndReset, ReadSince never alters the Chan.
+//
+// Note that Close() does not advance the version
+// (it leaves the closeVal alone), so pollers that
+// care about the open/closed status should
+// check isClosed as well.
+func (f *Chan[T]) ReadSince(lastVersion int64) (closeVal *T, isClosed bool, version int64, changed bool) {
+	f.rlockForRead()
+	closeVal = f.closeVal
+	isClosed = f.isClosed
+	version = f.version
+	f.runlockForRead()
+	changed = version > lastVersion
+	return
+}
+
+// Closed reports whether the Chan is closed. Unlike
+// Read, it does not count as observing a close (see
+// CloseWithAndWait), nor as a first read (see
+// OnFirstRead): it is a plain status check.
+//
+// Closed takes no lock at all, reading an atomic copy
+// of the status instead, for very hot polling loops.
+// The copy is updated together with the status under
+// the mutex, so Closed agrees with Read about every
+// close and every reset, including Reopen; a Closed
+// that races with one of them may return either
+// answer, just as a Read would.
+func (f *Chan[T]) Closed() bool {
+	return f.closed.Load()
+}
+
+// Version returns the current version, which
+// each update of the closeVal advances. See ReadSince.
+func (f *Chan[T]) Version() int64 {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	return f.version
+}
+
+// ClosedViaCloseWith reports the provenance of the
+// closeVal of a closed Chan. viaCloseWith is true if
+// the close supplied the closeVal explicitly, as
+// CloseWith, CloseWithFunc, SetAndClose, CloseAt and the
+// other CloseWith variants do, and false if the close
+// kept a pre-existing value, as Close, CloseIf,
+// TryClose, Seal and MarkClosed do. ok is false,
+// and viaCloseWith too, if the Chan is open.
+//
+// (For which Lifetime source closed a Chan,
+// see CloseCause instead.)
+func (f *Chan[T]) ClosedViaCloseWith() (viaCloseWith bool, ok bool) {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	if !f.isClosed {
+		return false, false
+	}
+	return f.viaCloseWith, true
+}
+
+// State is the observable state of a Chan,
+// as returned by ReadState.
+type State[T any] struct {
+	CloseVal *T
+	IsClosed bool
+	Version  int64
+}
+
+// ReadState is like ReadSince, but returns the
+// closeVal, isClosed status and version together in
+// a State, by value, without allocating. This keeps
+// call sites tidy as the state grows, since new fields
+// can be added to State without changing the signature.
+// ReadState counts as a Read for CloseWithAndWait
+// and OnFirstRead.
+func (f *Chan[T]) ReadState() (s State[T]) {
+	f.rlockForRead()
+	s = f.stateLocked()
+	f.runlockForRead()
+	return
+}
+
+// WithLockHeld calls fn with the current closeVal and
+// isClosed status while holding the Chan's mutex (for
+// reading), so the state cannot change until fn returns.
+// This lets advanced users compose the Chan's state
+// with invariants of their own, atomically, such as
+// copying it into a structure guarded by their own
+// lock, without exposing the mutex itself.
+//
+// The usual rules for code under a lock apply. fn
+// must be quick, and must not call any method on
+// this Chan, or it may deadlock; nor may it block on
+// anything that waits for the Chan to change. If fn
+// also takes a lock of its own, every goroutine must
+// take the two in the same order: this Chan's first,
+// as WithLockHeld does, and then the other. Other
+// readers may hold the mutex at the same time, so fn
+// may run concurrently with Read, and with another
+// WithLockHeld fn. WithLockHeld does not count as a Read
+// for CloseWithAndWait or OnFirstRead.
+func (f *Chan[T]) WithLockHeld(fn func(cur *T, closed bool)) {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	fn(f.closeVal, f.isClosed)
+}
+
+// ReadOrDefault is like Read, but returns def in
+// place of a nil closeVal. Readers that just want a
+// usable value can thus skip a nil check
+// at each call site. The isClosed status
+// is always reported truthfully.
+func (f *Chan[T]) ReadOrDefault(def *T) (closeVal *T, isClosed bool) {
+	closeVal, isClosed = f.Read()
+	if closeVal == nil {
+		closeVal = def
+	}
+	return
+}
+
+// ReadStable samples the closeVal n times in quick
+// succession, each with its own Read, and reports
+// stable false if any two samples differ according
+// to eq. It returns the last sample as val. This
+// is a diagnostic for verifying quiescence, or for
+// chasing a suspected race where the value flickers.
+//
+// eq is only applied to consecutive samples, so it
+// should be a true equivalence. A stable result
+// means no change was seen, not that none happened:
+// a Set and a Set back again between two samples
+// goes unnoticed. With n < 2, ReadStable reads
+// once and reports stable true.
+func (f *Chan[T]) ReadStable(n int, eq func(a, b *T) bool) (val *T, stable bool) {
+	val, _ = f.Read()
+	stable = true
+	for i := 1; i < n; i++ {
+		next, _ := f.Read()
+		if !eq(val, next) {
+			stable = false
+		}
+		val = next
+	}
+	return
+}
+
+// ConsumeCloseVal returns the current closeVal and
+// clears the Chan's internal reference to it, so that
+// the closeVal can be garbage collected once the
+// caller is done with it. This matters for long-lived
+// caches of finished Chans, where each retained
+// closeVal could pin a large object in memory.
+//
+// Subsequent Read() calls will return a nil closeVal
+// (until a new one is Set), so ConsumeCloseVal suits
+// a Chan with a single final reader. The open/closed
+// status is unchanged. Clearing the closeVal
+// counts as a change, and bumps the version.
+func (f *Chan[T]) ConsumeCloseVal() (closeVal *T) {
+	f.mut.Lock()
+	defer f.unlock()
+	closeVal = f.closeVal
+	f.observeLocked()
+	if f.frozen {
+		return
+	}
+	f.closeVal = nil
+	f.version++
+	f.recordLocked(kindSet, "ConsumeCloseVal", closeVal)
 	return
 }
 
@@ -308,23 +1053,487 @@ func (f *Chan[T]) ReadVersionAndReset(newCloseVal *T) (closeVal *T, version int6
 	f.mut.Lock()
 	closeVal = f.closeVal
 	version = f.version
+	f.observeLocked()
+	if f.sealed || f.frozen {
+		f.unlock()
+		return
+	}
 
-	f.isClosed = false
+	f.reopenLocked()
 	f.closeVal = newCloseVal
 	f.version++
-	f.mut.Unlock()
+	f.recordLocked(kindReset, "ReadVersionAndReset", closeVal)
+	f.unlock()
 	return
 }
 
 // ReadAndReset is the same as ReadVersionAndReset, except
 // that it doesn't return the version of the returned closeVal.
+//
+// Both reset methods leave the Chan open. If it was
+// closed, WhenClosed() will thereafter return a fresh
+// channel that is closed on the next Close;
+// anyone still holding the old channel will
+// see no further closes.
+//
+// A sealed Chan (see Seal) cannot be reset; the reset
+// methods then just return the closeVal (and version),
+// leaving the Chan closed and unchanged.
 func (f *Chan[T]) ReadAndReset(newCloseVal *T) (closeVal *T) {
 	f.mut.Lock()
 	closeVal = f.closeVal
+	f.observeLocked()
+	if f.sealed || f.frozen {
+		f.unlock()
+		return
+	}
 
-	f.isClosed = false
+	f.reopenLocked()
 	f.closeVal = newCloseVal
 	f.version++
+	f.recordLocked(kindReset, "ReadAndReset", closeVal)
+	f.unlock()
+	return
+}
+
+// Reset returns the Chan to the condition of a
+// fresh NewChan(closeVal): open, holding closeVal.
+// This is for object pools that recycle Chan instances
+// between uses. Unlike the ReadAndReset methods,
+// Reset returns nothing.
+//
+// Reset always installs a brand new WhenClosed channel,
+// even if the Chan was open. Any goroutine that stored
+// and is still blocked on a channel from an earlier
+// WhenClosed() call is not affected by the reset, and
+// will never be woken by later closes. This is one
+// more reason never to store the WhenClosed channel;
+// call WhenClosed() afresh each time.
+//
+// The version is bumped rather than zeroed, so that
+// version-based pollers notice the reset. A sealed
+// Chan (see Seal) is left unchanged.
+func (f *Chan[T]) Reset(closeVal *T) {
+	f.mut.Lock()
+	defer f.unlock()
+	if f.sealed || f.frozen {
+		return
+	}
+	old := f.closeVal
+	gen := f.gen
+	f.reopenLocked()
+	if f.gen == gen {
+		// was open: renew anyway.
+		f.renewLocked()
+	}
+	f.closeVal = closeVal
+	f.version++
+	f.recordLocked(kindReset, "Reset", old)
+}
+
+// ResetReuse is like Reset, but for pools that recycle
+// Chans at a high rate: it allocates a fresh WhenClosed
+// channel only if the old one was closed. If the Chan
+// was still open, its WhenClosed channel is kept, and
+// only the closeVal (and version) are reset.
+//
+// The subtle difference from Reset is in who is woken
+// by the next close. After Reset, goroutines still
+// blocked on a WhenClosed channel obtained before the
+// reset are never woken. After ResetReuse of an open
+// Chan, such goroutines are woken by the next close,
+// just as if there had been no reset, though they
+// may then Read a closeVal from the Chan's next use.
+// ResetReuse suits pools only where no goroutine can
+// still be waiting on a recycled Chan, which is
+// the usual case for a well-behaved pool. This holds
+// with WithNotifyChannelGuard too: the guarded channels
+// issued before a ResetReuse of an open Chan are
+// kept, and closed by the next close.
+//
+// A sealed Chan is left unchanged, as with Reset.
+func (f *Chan[T]) ResetReuse(closeVal *T) {
+	f.mut.Lock()
+	defer f.unlock()
+	if f.sealed || f.frozen {
+		return
+	}
+	old := f.closeVal
+	var kept []chan struct{}
+	if f.guard != nil && (!f.isClosed || f.marked) {
+		// the WhenClosed channel is reused, and
+		// so are the guarded ones standing in for it.
+		kept = f.guard.issued
+		f.guard.issued = nil
+	}
+	f.reopenLocked()
+	if kept != nil {
+		f.guard.issued = kept
+	}
+	f.closeVal = closeVal
+	f.version++
+	f.recordLocked(kindReset, "ResetReuse", old)
+}
+
+// closeLocked marks the Chan closed, closes the
+// WhenClosed channel, and notifies subscribers
+// of the closeVal. f.mut must be held, and
+// the Chan must be open (or only marked closed).
+func (f *Chan[T]) closeLocked() {
+	if !f.marked {
+		f.closedAt = f.getClock().Now()
+	}
+	f.viaCloseWith = true
+	f.isClosed = true
+	f.closed.Store(true)
+	f.marked = false
+	f.observed.Store(false)
+	f.observedCh = nil
+	close(f.whenClosed)
+	if f.guard != nil {
+		f.guard.closeAll()
+	}
+	for sub := range f.subs {
+		var delivered bool
+		if f.finalPriority {
+			delivered = sub.replace(f.closeVal)
+		} else {
+			delivered = sub.offer(f.closeVal)
+		}
+		if delivered {
+			f.observeLocked()
+		}
+	}
+}
+
+// closePlainLocked is closeLocked for the closes that
+// keep the existing closeVal, rather than supplying
+// one; see ClosedViaCloseWith. f.mut must be held.
+func (f *Chan[T]) closePlainLocked() {
+	f.closeLocked()
+	f.viaCloseWith = false
+}
+
+// observeLocked notes that a reader has seen the
+// Chan closed, waking any CloseWithAndWait.
+// f.mut must be held, at least for reading: of
+// several readers, only the one that flips
+// observed closes observedCh.
+func (f *Chan[T]) observeLocked() {
+	if !f.isClosed || f.observed.Load() {
+		return
+	}
+	if f.observed.CompareAndSwap(false, true) && f.observedCh != nil {
+		close(f.observedCh)
+	}
+}
+
+// reopenLocked marks the Chan open. If it was closed,
+// a fresh WhenClosed channel is made for the next
+// close. So it is too if WithNotifyChannelGuard
+// abandons channels issued for an open Chan, as
+// they are replaced all the same, and WhenClosedGen
+// callers must see a new generation. f.mut must be held.
+func (f *Chan[T]) reopenLocked() {
+	if (f.isClosed && !f.marked) || (f.guard != nil && len(f.guard.issued) > 0) {
+		f.renewLocked()
+	}
+	if f.isClosed {
+		f.openedAt = f.getClock().Now()
+	}
+	f.isClosed = false
+	f.closed.Store(false)
+	f.marked = false
+	f.closedAt = time.Time{}
+	f.causes = nil
+	f.lifetimeCause = LifetimeCauseNone
+	if f.guard != nil {
+		f.guard.abandon()
+	}
+}
+
+// renewLocked installs a fresh WhenClosed channel,
+// starting a new generation. f.mut must be held.
+func (f *Chan[T]) renewLocked() {
+	f.whenClosed = make(chan struct{})
+	f.gen++
+}
+
+// event describes one mutation of a Chan. Events
+// are recorded while f.mut is held, and delivered
+// to observers only after it has been released.
+type event[T any] struct {
+	kind    eventKind
+	op      string
+	when    time.Time
+	old     *T
+	new     *T
+	version int64
+
+	// path lists the lifecycle states the Chan passed
+	// through, if the operation changed its lifecycle.
+	path []Lifecycle
+
+	// bsubs are the buffered subscribers to receive
+	// new, as of when the event was recorded.
+	bsubs []*bufferedSub[T]
+
+	// unsub is the buffered subscriber whose channel
+	// a kindUnsubscribe event closes.
+	unsub *bufferedSub[T]
+
+	// matchers are the WhenMatches registrations
+	// to try on new.
+	matchers []*matcher[T]
+
+	// mirrors are the Chans from Mirror() to close
+	// with new.
+	mirrors []*Chan[T]
+
+	// broadcast is true for a close that closed the
+	// WhenClosed channel, and false for MarkClosed.
+	broadcast bool
+}
+
+// eventKind classifies the operations that mutate a Chan.
+type eventKind int
+
+const (
+	kindSet   eventKind = iota // closeVal updated, open/closed unchanged.
+	kindClose                  // Chan transitioned from open to closed.
+	kindReset                  // Chan was reset to open.
+	kindSeal                   // closed Chan was sealed.
+
+	kindUnsubscribe // buffered subscriber cancelled; not a mutation.
+	kindMatch       // WhenMatches registered; not a mutation.
+	kindFlush       // coalesced Sets notified; not a mutation.
+)
+
+// recordLocked wakes any goroutines waiting for
+// a change, and queues an event for the operation op
+// that just changed the Chan; old is the closeVal
+// from before op. f.mut must be held. The event
+// is delivered when the caller releases f.mut
+// with unlock(). Queuing is skipped if nobody is
+// observing the Chan. With a Sequence, the
+// version is drawn from it here.
+func (f *Chan[T]) recordLocked(kind eventKind, op string, old *T) {
+	changed := f.version != f.recordedVersion
+	if changed {
+		// op bumped the version: a change to the closeVal.
+		f.updates++
+	}
+	if f.seq != nil {
+		f.version = f.seq.Next()
+	}
+	f.recordedVersion = f.version
+	if DebugChecks {
+		f.checkLocked(op)
+	}
+	notify := f.notifyLocked(kind)
+	if f.changed != nil && notify {
+		close(f.changed)
+		f.changed = nil
+	}
+	if changed {
+		f.rememberLocked()
+	}
+	prev := f.lifecycle
+	f.lifecycle = f.lifecycleLocked()
+
+	broadcast := kind == kindClose && !f.marked
+	var mirrors []*Chan[T]
+	if broadcast {
+		mirrors = f.mirrors
+		f.mirrors = nil
+	}
+	if f.auditLog == nil && f.logger == nil && f.span == nil && f.transitionHook == nil &&
+		len(f.bsubs) == 0 && len(f.onClose) == 0 && len(f.matchers) == 0 &&
+		len(mirrors) == 0 && len(f.targets) == 0 {
+		return
+	}
+	var path []Lifecycle
+	switch {
+	case kind == kindReset:
+		path = []Lifecycle{prev, LifecycleResetPending, f.lifecycle}
+	case prev != f.lifecycle:
+		path = []Lifecycle{prev, f.lifecycle}
+	}
+	ev := event[T]{
+		kind:      kind,
+		op:        op,
+		when:      f.getClock().Now(),
+		old:       old,
+		new:       f.closeVal,
+		version:   f.version,
+		path:      path,
+		mirrors:   mirrors,
+		broadcast: broadcast,
+	}
+	if notify && (kind != kindClose || broadcast) {
+		// a MarkClosed notifies nobody until the Close
+		// that completes its broadcast.
+		ev.bsubs = f.bsubsFor(kind)
+		ev.matchers = f.matchersFor(kind)
+	}
+	f.pending = append(f.pending, ev)
+}
+
+// bsubsFor returns a snapshot of the buffered
+// subscribers that should receive an event of
+// the given kind: only sets and closes are sent.
+func (f *Chan[T]) bsubsFor(kind eventKind) []*bufferedSub[T] {
+	if len(f.bsubs) == 0 || (kind != kindSet && kind != kindClose) {
+		return nil
+	}
+	return slices.Clone(f.bsubs)
+}
+
+// matchersFor returns a snapshot of the WhenMatches
+// registrations to try after an event of the given kind.
+func (f *Chan[T]) matchersFor(kind eventKind) []*matcher[T] {
+	if len(f.matchers) == 0 || (kind != kindSet && kind != kindClose && kind != kindReset) {
+		return nil
+	}
+	return slices.Clone(f.matchers)
+}
+
+// unlock releases f.mut, and then delivers any
+// events recorded by the critical section.
+// Delivery happens outside the lock, so observers
+// may safely call methods on the Chan. Should
+// such a call record further events, they are
+// queued and delivered in order by whichever
+// goroutine is already delivering.
+func (f *Chan[T]) unlock() {
+	if f.emitting || len(f.pending) == 0 {
+		f.mut.Unlock()
+		return
+	}
+	f.emitting = true
+	for len(f.pending) > 0 {
+		evs := f.pending
+		f.pending = nil
+		f.mut.Unlock()
+		for _, ev := range evs {
+			f.deliver(ev)
+		}
+		f.mut.Lock()
+	}
+	f.emitting = false
 	f.mut.Unlock()
+}
+
+// deliver hands a single event to each observer.
+// f.mut must not be held.
+func (f *Chan[T]) deliver(ev event[T]) {
+	switch ev.kind {
+	case kindUnsubscribe:
+		close(ev.unsub.ch)
+		return
+	case kindMatch:
+		f.match(ev.matchers, ev.new)
+		return
+	case kindFlush:
+		for _, sub := range ev.bsubs {
+			sub.send(ev.new, f.overflow)
+		}
+		f.match(ev.matchers, ev.new)
+		return
+	}
+	if f.auditLog != nil {
+		protect(func() {
+			f.auditLog(AuditEntry[T]{
+				Op:      ev.op,
+				Time:    ev.when,
+				Actor:   f.name,
+				Old:     ev.old,
+				New:     ev.new,
+				Version: ev.version,
+			})
+		})
+	}
+	if f.logger != nil {
+		protect(func() { f.logger(f.describe(ev), ev.version) })
+	}
+	if f.span != nil {
+		protect(func() { f.span.record(ev) })
+	}
+	if f.transitionHook != nil {
+		for i := 1; i < len(ev.path); i++ {
+			protect(func() { f.transitionHook(ev.path[i-1], ev.path[i]) })
+		}
+	}
+	if ev.kind == kindClose && ev.broadcast {
+		for _, fn := range f.onClose {
+			protect(func() { fn(ev.new) })
+		}
+	}
+	for _, sub := range ev.bsubs {
+		if ev.broadcast && f.finalPriority {
+			sub.replace(ev.new)
+			continue
+		}
+		sub.send(ev.new, f.overflow)
+	}
+	f.match(ev.matchers, ev.new)
+	for _, m := range ev.mirrors {
+		m.CloseWith(ev.new)
+	}
+	if ev.kind == kindClose && ev.broadcast {
+		f.sendTargets(ev.new)
+	}
+}
+
+// readAndWatch returns the current closeVal and
+// isClosed status, along with a channel that will
+// be closed on the next change to the Chan. Obtaining
+// all three under one lock means a waiter
+// cannot miss a change that happens between
+// its Read and its wait.
+func (f *Chan[T]) readAndWatch() (closeVal *T, isClosed bool, changed <-chan struct{}) {
+	s, changed := f.watchState()
+	return s.CloseVal, s.IsClosed, changed
+}
+
+// watchState is readAndWatch, but
+// reporting the version as well.
+//
+// Waiters share one changed channel per change, so
+// only the first waiter after each change takes the
+// write lock, to allocate it; the rest, like Read,
+// take only the read lock.
+func (f *Chan[T]) watchState() (s State[T], changed <-chan struct{}) {
+	s, _, changed = f.watchUpdates()
 	return
 }
+
+// watchUpdates is watchState, but also
+// reporting the count of updates.
+func (f *Chan[T]) watchUpdates() (s State[T], updates int64, changed <-chan struct{}) {
+	f.rlockForRead()
+	if f.changed != nil {
+		s, updates, changed = f.stateLocked(), f.updates, f.changed
+		f.runlockForRead()
+		return
+	}
+	f.mut.RUnlock()
+
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if f.changed == nil {
+		f.changed = make(chan struct{})
+	}
+	f.observeLocked()
+	return f.stateLocked(), f.updates, f.changed
+}
+
+// stateLocked returns the current State.
+// f.mut must be held, at least for reading.
+func (f *Chan[T]) stateLocked() State[T] {
+	return State[T]{
+		CloseVal: f.closeVal,
+		IsClosed: f.isClosed,
+		Version:  f.version,
+	}
+}
@@ -2,11 +2,15 @@ package loquet_test
 
 import (
 	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/glycerine/loquet"
 )
 
+var errTimeout = fmt.Errorf("timeout")
+
 type Message struct {
 	Err error
 }
@@ -63,3 +67,588 @@ func ExLoquetChanUse() {
 func Test001(t *testing.T) {
 	ExLoquetChanUse()
 }
+
+func TestCloseWithFunc(t *testing.T) {
+	status := loquet.NewChan[int](nil)
+	one := 1
+	status.Set(&one)
+
+	err := status.CloseWithFunc(func(cur *int) *int {
+		next := *cur + 1
+		return &next
+	})
+	if err != nil {
+		t.Fatalf("expected nil error on first close, got %v", err)
+	}
+	val, isClosed := status.Read()
+	if !isClosed || *val != 2 {
+		t.Fatalf("expected closed with 2, got %v, %v", *val, isClosed)
+	}
+
+	called := false
+	err = status.CloseWithFunc(func(cur *int) *int {
+		called = true
+		return cur
+	})
+	if err != loquet.ErrAlreadyClosed {
+		t.Fatalf("expected ErrAlreadyClosed, got %v", err)
+	}
+	if called {
+		t.Fatalf("fn should not be called on an already closed Chan")
+	}
+}
+
+func TestCloseAfterReadAndReset(t *testing.T) {
+	// regression: the reset methods used to leave the
+	// already closed WhenClosed channel in place, so
+	// that the next close panicked.
+	c := loquet.NewChan[int](nil)
+	c.Close()
+	c.ReadAndReset(nil)
+	select {
+	case <-c.WhenClosed():
+		t.Fatalf("expected a fresh WhenClosed channel after the reset")
+	default:
+	}
+	c.Close()
+	<-c.WhenClosed()
+
+	c.ReadVersionAndReset(nil)
+	c.Close()
+	<-c.WhenClosed()
+}
+
+func TestSetFunc(t *testing.T) {
+	zero := 0
+	counter := loquet.NewChan[int](&zero)
+	incr := func(cur *int) *int {
+		next := *cur + 1
+		return &next
+	}
+
+	const n = 100
+	done := make(chan struct{})
+	for i := 0; i < n; i++ {
+		go func() {
+			counter.SetFunc(incr)
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		<-done
+	}
+	val, _ := counter.Read()
+	if *val != n {
+		t.Fatalf("expected %v after concurrent SetFunc, got %v", n, *val)
+	}
+
+	counter.Close()
+	old := counter.SetFuncIfOpen(func(cur *int) *int {
+		t.Fatalf("fn should not be called on a closed Chan")
+		return cur
+	})
+	if *old != n {
+		t.Fatalf("expected old to be %v, got %v", n, *old)
+	}
+}
+
+func TestReadOrDefault(t *testing.T) {
+	def := &Message{Err: fmt.Errorf("default")}
+	status := loquet.NewChan[Message](nil)
+
+	val, isClosed := status.ReadOrDefault(def)
+	if val != def || isClosed {
+		t.Fatalf("expected default and open")
+	}
+
+	msg := &Message{}
+	status.CloseWith(msg)
+	val, isClosed = status.ReadOrDefault(def)
+	if val != msg || !isClosed {
+		t.Fatalf("expected msg and closed")
+	}
+}
+
+func TestSwap(t *testing.T) {
+	one, two, three := 1, 2, 3
+	c := loquet.NewChan(&one)
+	if old := c.Swap(&two); old != &one {
+		t.Fatalf("expected Swap to return the previous closeVal")
+	}
+	c.Close()
+	if old := c.Swap(&three); old != &two {
+		t.Fatalf("expected Swap to return the previous closeVal when closed")
+	}
+	val, isClosed := c.Read()
+	if val != &three || !isClosed {
+		t.Fatalf("expected Swap to replace the closeVal of a closed Chan")
+	}
+}
+
+func TestReadSince(t *testing.T) {
+	one := 1
+	c := loquet.NewChan[int](nil)
+	_, _, v0, changed := c.ReadSince(-1)
+	if !changed {
+		t.Fatalf("expected changed against an earlier version")
+	}
+	if _, _, _, changed = c.ReadSince(v0); changed {
+		t.Fatalf("expected no change without a Set")
+	}
+	c.Set(&one)
+	val, _, v1, changed := c.ReadSince(v0)
+	if !changed || v1 <= v0 || *val != 1 {
+		t.Fatalf("expected a change to 1 after Set")
+	}
+}
+
+func TestTryCloseWithSingleWinner(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	const n = 50
+	wins := make(chan int, n)
+	for i := 0; i < n; i++ {
+		go func(i int) {
+			v := i
+			if c.TryCloseWith(&v) {
+				wins <- i
+				return
+			}
+			wins <- -1
+		}(i)
+	}
+	winner := -1
+	for i := 0; i < n; i++ {
+		if w := <-wins; w >= 0 {
+			if winner >= 0 {
+				t.Fatalf("more than one winner: %v and %v", winner, w)
+			}
+			winner = w
+		}
+	}
+	if val, _ := c.Read(); winner < 0 || *val != winner {
+		t.Fatalf("expected the winner's closeVal %v", winner)
+	}
+}
+
+func TestConsumeCloseVal(t *testing.T) {
+	msg := &Message{}
+	c := loquet.NewChan[Message](nil)
+	c.CloseWith(msg)
+
+	if got := c.ConsumeCloseVal(); got != msg {
+		t.Fatalf("expected ConsumeCloseVal to return the closeVal")
+	}
+	val, isClosed := c.Read()
+	if val != nil || !isClosed {
+		t.Fatalf("expected a nil closeVal on a still closed Chan")
+	}
+}
+
+func TestReset(t *testing.T) {
+	one, two := 1, 2
+	c := loquet.NewChan(&one)
+	before := c.WhenClosed()
+	c.Close()
+	c.Reset(&two)
+
+	val, isClosed := c.Read()
+	if isClosed || val != &two {
+		t.Fatalf("expected open with the new closeVal after Reset")
+	}
+	if c.WhenClosed() == before {
+		t.Fatalf("expected a fresh WhenClosed channel after Reset")
+	}
+
+	// even an open Chan gets a fresh channel.
+	stale := c.WhenClosed()
+	c.Reset(nil)
+	c.Close()
+	select {
+	case <-stale:
+		t.Fatalf("a stored WhenClosed channel should not fire after Reset")
+	default:
+	}
+	<-c.WhenClosed()
+}
+
+func TestSetAndClose(t *testing.T) {
+	one, two := 1, 2
+	c := loquet.NewChan[int](nil)
+	c.SetAndClose(&one)
+	val, isClosed := c.Read()
+	if !isClosed || val != &one {
+		t.Fatalf("expected SetAndClose to close with 1")
+	}
+	c.SetAndClose(&two)
+	val, isClosed = c.Read()
+	if !isClosed || val != &two {
+		t.Fatalf("expected SetAndClose to update a closed Chan to 2")
+	}
+}
+
+func TestMarkClosed(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	if was := c.MarkClosed(); was {
+		t.Fatalf("expected was false on an open Chan")
+	}
+	if was := c.MarkClosed(); !was {
+		t.Fatalf("expected was true on the second MarkClosed")
+	}
+	if _, isClosed := c.Read(); !isClosed {
+		t.Fatalf("expected Read to report isClosed after MarkClosed")
+	}
+	select {
+	case <-c.WhenClosed():
+		t.Fatalf("MarkClosed must not close WhenClosed")
+	default:
+	}
+	one := 1
+	if err := c.CloseWith(&one); err != loquet.ErrAlreadyClosed {
+		t.Fatalf("expected ErrAlreadyClosed from CloseWith, got %v", err)
+	}
+
+	// Close completes the deferred broadcast.
+	if err := c.Close(); err != nil {
+		t.Fatalf("expected Close to broadcast a marked Chan, got %v", err)
+	}
+	select {
+	case <-c.WhenClosed():
+	default:
+		t.Fatalf("expected WhenClosed to be closed by Close")
+	}
+	if err := c.Close(); err != loquet.ErrAlreadyClosed {
+		t.Fatalf("expected ErrAlreadyClosed, got %v", err)
+	}
+}
+
+func TestWhenClosedGen(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	ch0, gen0 := c.WhenClosedGen()
+	if gen0 != 0 || ch0 != c.WhenClosed() {
+		t.Fatalf("expected generation 0 and the WhenClosed channel")
+	}
+	c.Close()
+	if c.WhenClosedGeneration() != gen0 {
+		t.Fatalf("Close must not change the generation")
+	}
+	c.ReadAndReset(nil)
+	ch1, gen1 := c.WhenClosedGen()
+	if gen1 != gen0+1 || ch1 == ch0 {
+		t.Fatalf("expected a reset to replace the channel and bump the generation, got %v", gen1)
+	}
+	c.Reset(nil) // always renews, even when open.
+	if _, gen2 := c.WhenClosedGen(); gen2 != gen1+1 {
+		t.Fatalf("expected Reset to bump the generation once, got %v", gen2)
+	}
+}
+
+func TestReadStable(t *testing.T) {
+	eq := func(a, b *int) bool { return *a == *b }
+	one := 1
+	c := loquet.NewChan[int](&one)
+	if val, stable := c.ReadStable(5, eq); !stable || *val != 1 {
+		t.Fatalf("expected a quiet Chan to be stable at 1")
+	}
+
+	// a flickering value is noticed, with eq called on every sample.
+	calls := 0
+	flicker := func(a, b *int) bool {
+		calls++
+		two := 2
+		c.Set(&two)
+		return *a == *b
+	}
+	if _, stable := c.ReadStable(3, flicker); stable || calls != 2 {
+		t.Fatalf("expected unstable after %v calls, got stable=%v", calls, stable)
+	}
+}
+
+func TestCloseWithSwap(t *testing.T) {
+	one, two, three := 1, 2, 3
+	c := loquet.NewChan[int](&one)
+	c.Set(&two)
+	old, err := c.CloseWithSwap(&three)
+	if err != nil || old != &two {
+		t.Fatalf("expected the intermediate value 2 and nil error, got %v, %v", old, err)
+	}
+	old, err = c.CloseWithSwap(&one)
+	if err != loquet.ErrAlreadyClosed || old != &three {
+		t.Fatalf("expected the current value 3 and ErrAlreadyClosed, got %v, %v", old, err)
+	}
+	if val, _ := c.Read(); val != &three {
+		t.Fatalf("a failed CloseWithSwap must not change the closeVal")
+	}
+}
+
+// mutexChan is the single-mutex design that Chan used
+// before its RWMutex, kept as a baseline for
+// BenchmarkReadParallel.
+type mutexChan struct {
+	mut      sync.Mutex
+	closeVal *int
+	isClosed bool
+}
+
+func (m *mutexChan) Read() (closeVal *int, isClosed bool) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	return m.closeVal, m.isClosed
+}
+
+// withoutDebugChecks turns off the DebugChecks that
+// TestMain turns on, for the duration of a benchmark.
+func withoutDebugChecks(b *testing.B) {
+	saved := loquet.DebugChecks
+	loquet.DebugChecks = false
+	b.Cleanup(func() { loquet.DebugChecks = saved })
+}
+
+func BenchmarkReadParallel(b *testing.B) {
+	withoutDebugChecks(b)
+	one := 1
+	c := loquet.NewChan[int](&one)
+	c.Close()
+	c.Read()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Read()
+		}
+	})
+}
+
+func BenchmarkReadParallelMutexBaseline(b *testing.B) {
+	one := 1
+	m := &mutexChan{closeVal: &one, isClosed: true}
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			m.Read()
+		}
+	})
+}
+
+func TestClosedAndVersion(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	if c.Closed() || c.Version() != 0 {
+		t.Fatalf("expected a fresh Chan open at version 0")
+	}
+	one := 1
+	c.Set(&one)
+	c.Close()
+	if !c.Closed() || c.Version() != 1 {
+		t.Fatalf("expected closed at version 1, got %v, %v", c.Closed(), c.Version())
+	}
+	c.ReadAndReset(nil)
+	if c.Closed() {
+		t.Fatalf("expected Closed to follow a reset")
+	}
+	c.MarkClosed()
+	if !c.Closed() {
+		t.Fatalf("expected Closed after MarkClosed")
+	}
+}
+
+func BenchmarkClosed(b *testing.B) {
+	withoutDebugChecks(b)
+	c := loquet.NewChan[int](nil)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Closed()
+		}
+	})
+}
+
+// BenchmarkClosedViaRead is the locking way to poll
+// the status, for comparison with BenchmarkClosed.
+func BenchmarkClosedViaRead(b *testing.B) {
+	withoutDebugChecks(b)
+	c := loquet.NewChan[int](nil)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			c.Read()
+		}
+	})
+}
+
+func TestReadState(t *testing.T) {
+	one := 1
+	c := loquet.NewChan[int](nil)
+	c.CloseWith(&one)
+	s := c.ReadState()
+	if s.CloseVal != &one || !s.IsClosed || s.Version != 1 {
+		t.Fatalf("unexpected state %+v", s)
+	}
+}
+
+func TestReadStateDoesNotAllocate(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	c.Read()
+	if n := testing.AllocsPerRun(100, func() { c.ReadState() }); n != 0 {
+		t.Fatalf("expected no allocations, got %v", n)
+	}
+}
+
+func TestTryClose(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+
+	// Equal calls eq with the read lock held,
+	// which makes TryClose give up.
+	c.Equal(c, func(a, b *int) bool {
+		if closed, acquired := c.TryClose(); closed || acquired {
+			t.Fatalf("expected TryClose not to acquire a held mutex")
+		}
+		return true
+	})
+
+	if closed, acquired := c.TryClose(); !closed || !acquired {
+		t.Fatalf("expected TryClose to close an uncontended Chan")
+	}
+	if closed, acquired := c.TryClose(); closed || !acquired {
+		t.Fatalf("expected closed false on an already closed Chan")
+	}
+	if !c.Closed() {
+		t.Fatalf("expected the Chan to be closed")
+	}
+}
+
+func TestCloseIf(t *testing.T) {
+	isDone := func(cur *int) bool { return cur != nil && *cur == 100 }
+	c := loquet.NewChan[int](nil)
+	if c.CloseIf(isDone) {
+		t.Fatalf("expected no close on a nil closeVal")
+	}
+	fifty, hundred := 50, 100
+	c.Set(&fifty)
+	if c.CloseIf(isDone) || c.Closed() {
+		t.Fatalf("expected no close at 50")
+	}
+	c.Set(&hundred)
+	if !c.CloseIf(isDone) {
+		t.Fatalf("expected a close at 100")
+	}
+	if val, isClosed := c.Read(); !isClosed || val != &hundred {
+		t.Fatalf("expected closed keeping 100")
+	}
+	if c.CloseIf(isDone) {
+		t.Fatalf("expected closed false on an already closed Chan")
+	}
+}
+
+func TestImmutableAfterClose(t *testing.T) {
+	one, two := 1, 2
+	c := loquet.NewChan[int](nil, loquet.WithImmutableAfterClose[int]())
+	c.Set(&one)
+	c.Close()
+	if old := c.Set(&two); old != &one {
+		t.Fatalf("expected Set to still report the current value")
+	}
+	c.SetFunc(func(*int) *int { return &two })
+	if val, _ := c.Read(); val != &one || c.Version() != 1 {
+		t.Fatalf("expected the closeVal to stay 1 after close")
+	}
+	c.ReadAndReset(nil)
+	c.Set(&two)
+	if val, _ := c.Read(); val != &two {
+		t.Fatalf("expected Set to work again once reset")
+	}
+}
+
+func TestResetReuse(t *testing.T) {
+	one := 1
+	c := loquet.NewChan[int](nil)
+	before := c.WhenClosed()
+	c.ResetReuse(&one)
+	if c.WhenClosed() != before {
+		t.Fatalf("expected an open Chan to keep its WhenClosed channel")
+	}
+	if val, _ := c.Read(); val != &one || c.Version() != 1 {
+		t.Fatalf("expected closeVal 1 at version 1")
+	}
+	c.Close()
+	c.ResetReuse(nil)
+	if c.WhenClosed() == before || c.Closed() {
+		t.Fatalf("expected a closed Chan to get a fresh WhenClosed channel")
+	}
+}
+
+func BenchmarkReset(b *testing.B) {
+	withoutDebugChecks(b)
+	c := loquet.NewChan[int](nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.Reset(nil)
+	}
+}
+
+func BenchmarkResetReuse(b *testing.B) {
+	withoutDebugChecks(b)
+	c := loquet.NewChan[int](nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c.ResetReuse(nil)
+	}
+}
+
+func TestCloseWithIfVersion(t *testing.T) {
+	one, two := 1, 2
+	c := loquet.NewChan[int](nil)
+	seen := c.ReadState().Version
+	c.Set(&one) // somebody else changed it.
+	if c.CloseWithIfVersion(seen, &two) {
+		t.Fatalf("expected a stale version to prevent the close")
+	}
+	seen = c.ReadState().Version
+	if !c.CloseWithIfVersion(seen, &two) {
+		t.Fatalf("expected the current version to allow the close")
+	}
+	if val, isClosed := c.Read(); !isClosed || val != &two {
+		t.Fatalf("expected closed with 2")
+	}
+	if c.CloseWithIfVersion(c.Version(), &one) {
+		t.Fatalf("expected false on an already closed Chan")
+	}
+}
+
+func TestClosedViaCloseWith(t *testing.T) {
+	one := 1
+	c := loquet.NewChan[int](nil)
+	if via, ok := c.ClosedViaCloseWith(); via || ok {
+		t.Fatalf("expected false, false on an open Chan")
+	}
+	c.CloseWith(&one)
+	if via, ok := c.ClosedViaCloseWith(); !via || !ok {
+		t.Fatalf("expected CloseWith to be reported")
+	}
+
+	c.ReadAndReset(&one)
+	c.Close()
+	if via, ok := c.ClosedViaCloseWith(); via || !ok {
+		t.Fatalf("expected a plain Close to be reported")
+	}
+}
+
+func TestWithLockHeld(t *testing.T) {
+	one, two := 1, 2
+	c := loquet.NewChan[int](&one)
+
+	done := make(chan struct{})
+	c.WithLockHeld(func(cur *int, closed bool) {
+		if cur != &one || closed {
+			t.Errorf("expected the open state with 1")
+		}
+		go func() {
+			defer close(done)
+			c.CloseWith(&two) // must wait for fn to return.
+		}()
+		select {
+		case <-done:
+			t.Errorf("expected CloseWith to wait for the lock")
+		case <-time.After(20 * time.Millisecond):
+		}
+	})
+	<-done
+	c.WithLockHeld(func(cur *int, closed bool) {
+		if cur != &two || !closed {
+			t.Errorf("expected the closed state with 2")
+		}
+	})
+}
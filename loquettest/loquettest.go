@@ -0,0 +1,97 @@
+// Package loquettest provides helpers for testing
+// code that uses loquet.Chan. Each helper takes a
+// snapshot of the Chan, compares it with what the
+// test expects, and reports any
+// mismatch through t.Errorf, with a message that
+// shows what was found. The snapshot is taken
+// without calling Read, which would count as a read
+// of the Chan: it would fire the OnFirstRead funcs,
+// and mark a close observed, releasing any
+// CloseWithAndWait. An assertion thus never changes
+// the behavior under test. Like the t.Error methods,
+// the helpers do not stop the test; each returns
+// ok, so that a test may stop itself:
+//
+//	if !loquettest.AssertClosed(t, status) {
+//	    t.FailNow()
+//	}
+package loquettest
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+// AssertClosed checks that c is closed, reporting
+// c's current closeVal if it is not.
+func AssertClosed[T any](t testing.TB, c *loquet.Chan[T]) (ok bool) {
+	t.Helper()
+	val, isClosed := snapshot(c)
+	if !isClosed {
+		t.Errorf("%v: expected closed, but it is open with closeVal %v",
+			describe(c), show(val))
+	}
+	return isClosed
+}
+
+// AssertOpen checks that c is open, reporting
+// c's final closeVal if it is not.
+func AssertOpen[T any](t testing.TB, c *loquet.Chan[T]) (ok bool) {
+	t.Helper()
+	val, isClosed := snapshot(c)
+	if isClosed {
+		t.Errorf("%v: expected open, but it is closed with closeVal %v",
+			describe(c), show(val))
+	}
+	return !isClosed
+}
+
+// AssertCloseValEquals checks that c's closeVal equals
+// want, comparing the values pointed to with
+// reflect.DeepEqual, so that two distinct pointers
+// to equal values match. A nil want matches only a
+// nil closeVal. The open/closed status is not
+// checked; combine with AssertClosed for that.
+func AssertCloseValEquals[T any](t testing.TB, c *loquet.Chan[T], want *T) (ok bool) {
+	t.Helper()
+	val, isClosed := snapshot(c)
+	if !reflect.DeepEqual(val, want) {
+		status := "open"
+		if isClosed {
+			status = "closed"
+		}
+		t.Errorf("%v: expected closeVal %v, got %v (Chan is %v)",
+			describe(c), show(want), show(val), status)
+		return false
+	}
+	return true
+}
+
+// snapshot returns c's closeVal and isClosed status
+// together, as Read does, but without counting as a
+// read of c.
+func snapshot[T any](c *loquet.Chan[T]) (val *T, isClosed bool) {
+	c.WithLockHeld(func(cur *T, closed bool) {
+		val, isClosed = cur, closed
+	})
+	return
+}
+
+// describe names c in messages.
+func describe[T any](c *loquet.Chan[T]) string {
+	if name := c.Name(); name != "" {
+		return fmt.Sprintf("loquet.Chan %q", name)
+	}
+	return "loquet.Chan"
+}
+
+// show formats a closeVal, dereferenced.
+func show[T any](val *T) string {
+	if val == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("%+v", *val)
+}
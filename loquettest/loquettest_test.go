@@ -0,0 +1,88 @@
+package loquettest_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/glycerine/loquet"
+	"github.com/glycerine/loquet/loquettest"
+)
+
+// recordingTB captures the failures reported
+// by the helpers, instead of failing the test.
+type recordingTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordingTB) Helper() {}
+
+func (r *recordingTB) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertClosedAndOpen(t *testing.T) {
+	one := 1
+	c := loquet.NewChan(&one, loquet.WithName[int]("job-42"))
+
+	r := &recordingTB{TB: t}
+	if loquettest.AssertClosed(r, c) {
+		t.Fatalf("expected AssertClosed to fail on an open Chan")
+	}
+	if !loquettest.AssertOpen(r, c) {
+		t.Fatalf("expected AssertOpen to pass on an open Chan")
+	}
+	if len(r.errors) != 1 ||
+		!strings.Contains(r.errors[0], `"job-42": expected closed`) ||
+		!strings.Contains(r.errors[0], "closeVal 1") {
+		t.Fatalf("unexpected failures: %q", r.errors)
+	}
+
+	c.Close()
+	r = &recordingTB{TB: t}
+	if !loquettest.AssertClosed(r, c) || loquettest.AssertOpen(r, c) {
+		t.Fatalf("expected only AssertOpen to fail on a closed Chan")
+	}
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "expected open") {
+		t.Fatalf("unexpected failures: %q", r.errors)
+	}
+}
+
+func TestAssertCloseValEquals(t *testing.T) {
+	one, otherOne, two := 1, 1, 2
+	c := loquet.NewChan[int](nil)
+	c.CloseWith(&one)
+
+	r := &recordingTB{TB: t}
+	if !loquettest.AssertCloseValEquals(r, c, &otherOne) {
+		t.Fatalf("expected equal values behind distinct pointers to match")
+	}
+	if loquettest.AssertCloseValEquals(r, c, &two) ||
+		loquettest.AssertCloseValEquals(r, c, nil) {
+		t.Fatalf("expected mismatches to fail")
+	}
+	if len(r.errors) != 2 ||
+		!strings.Contains(r.errors[0], "expected closeVal 2, got 1 (Chan is closed)") ||
+		!strings.Contains(r.errors[1], "expected closeVal <nil>, got 1") {
+		t.Fatalf("unexpected failures: %q", r.errors)
+	}
+}
+
+func TestAssertionsDoNotCountAsReads(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	fired := false
+	c.OnFirstRead(func() { fired = true })
+
+	r := &recordingTB{TB: t}
+	loquettest.AssertOpen(r, c)
+	c.Close()
+	loquettest.AssertClosed(r, c)
+	loquettest.AssertCloseValEquals(r, c, nil)
+	if len(r.errors) != 0 {
+		t.Fatalf("unexpected failures: %q", r.errors)
+	}
+	if fired {
+		t.Fatalf("expected the assertions not to fire OnFirstRead")
+	}
+}
@@ -0,0 +1,18 @@
+package loquet
+
+// noCopy may be embedded into structs which must not
+// be copied after first use. It has no fields and
+// costs nothing at run time; its only purpose is the
+// Lock and Unlock methods, which make the copylocks
+// check of go vet report any copy of the
+// containing struct by value.
+//
+// See https://golang.org/issues/8005#issuecomment-190753527
+// for details.
+type noCopy struct{}
+
+// Lock is a no-op used by go vet's -copylocks checker.
+func (*noCopy) Lock() {}
+
+// Unlock is a no-op used by go vet's -copylocks checker.
+func (*noCopy) Unlock() {}
@@ -0,0 +1,25 @@
+package loquet_test
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestVetCatchesChanCopy(t *testing.T) {
+	if testing.Short() {
+		t.Skip("runs go vet")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go tool not found")
+	}
+	out, err := exec.Command(goTool, "vet", "./testdata/copychan").CombinedOutput()
+	if err == nil {
+		t.Fatalf("expected go vet to reject copying a Chan by value")
+	}
+	if !strings.Contains(string(out), "copies lock value") &&
+		!strings.Contains(string(out), "passes lock by value") {
+		t.Fatalf("expected a copylocks report from go vet, got:\n%s", out)
+	}
+}
@@ -0,0 +1,43 @@
+package loquet
+
+// Option configures a Chan at construction time.
+// Options are passed to NewChan after the
+// initial closeVal. Since most options do not
+// mention T in their arguments, the type parameter
+// usually has to be given explicitly, as in
+//
+//	status := loquet.NewChan(msg, loquet.WithName[Message]("job-42"))
+type Option[T any] func(f *Chan[T])
+
+// WithName gives the Chan a name, which identifies
+// it as the actor in audit entries. The name
+// is otherwise unused; see Chan.Name().
+func WithName[T any](name string) Option[T] {
+	return func(f *Chan[T]) {
+		f.name = name
+	}
+}
+
+// Name returns the name given by WithName, if any.
+func (f *Chan[T]) Name() string {
+	// name is immutable after NewChan, no lock needed.
+	return f.name
+}
+
+// WithImmutableAfterClose makes Set behave as SetIfOpen,
+// and SetFunc as SetFuncIfOpen, so that a closed Chan's
+// closeVal can no longer be changed by them. This
+// restores, for the whole Chan, the guarantee that every
+// Read after a close sees the same closeVal, without
+// auditing every Set call site. The updates are
+// dropped silently, as SetIfOpen drops them.
+//
+// The explicit exceptions remain: Swap and SetAndClose
+// still update a closed Chan, as their names promise.
+// A reset re-opens the Chan, after which Set
+// works normally until the next close.
+func WithImmutableAfterClose[T any]() Option[T] {
+	return func(f *Chan[T]) {
+		f.immutableAfterClose = true
+	}
+}
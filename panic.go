@@ -0,0 +1,38 @@
+package loquet
+
+// OnCallbackPanic, if not nil, is called with the
+// recovered value whenever a user-provided callback
+// panics. Callbacks are the funcs that a Chan calls on
+// the user's behalf: those from WithOnClose,
+// WithAuditLog, WithLogger, WithTransitionHook,
+// WhenClosedFunc, OnFirstRead and WhenMatches,
+// and SpanRecorders; and the funcs that the package
+// calls on goroutines of its own: the predicate of
+// Filter, the fn of FlatMap, the compute of
+// WatchDerivedScalar, and the load and onErr of
+// NewConfigChan, on reload.
+// Such a panic is always recovered, so that it can
+// neither crash the goroutine that happened to trigger
+// the callback (the closer, say, or a reader), nor
+// leave the Chan stuck with undelivered events. The
+// remaining callbacks for the same event still run.
+//
+// With OnCallbackPanic nil, the default, recovered
+// panics are silently discarded. Set it to log them,
+// or to re-panic in tests. Like DebugChecks, set it
+// before use, for example in main or TestMain, and
+// do not change it while Chans are in use.
+var OnCallbackPanic func(recovered any)
+
+// protect calls fn, recovering any panic and
+// reporting it to OnCallbackPanic.
+func protect(fn func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			if hook := OnCallbackPanic; hook != nil {
+				hook(r)
+			}
+		}
+	}()
+	fn()
+}
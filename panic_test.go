@@ -0,0 +1,104 @@
+package loquet_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestCallbackPanicRecovered(t *testing.T) {
+	var mu sync.Mutex
+	var recovered []any
+	loquet.OnCallbackPanic = func(r any) {
+		mu.Lock()
+		recovered = append(recovered, r)
+		mu.Unlock()
+	}
+	defer func() { loquet.OnCallbackPanic = nil }()
+
+	// after is only touched by the closing goroutine.
+	var after int
+	c := loquet.NewChan[int](nil,
+		loquet.WithOnClose(func(*int) { panic("onClose") }),
+		loquet.WithOnClose(func(v *int) { after = *v }),
+	)
+	c.OnFirstRead(func() { panic("onFirstRead") })
+	c.WhenClosedFunc(func(*int) { panic("whenClosedFunc") })
+
+	c.Read() // must not panic.
+	one := 1
+	if err := c.CloseWith(&one); err != nil {
+		t.Fatalf("expected the close to succeed despite the panic, got %v", err)
+	}
+	if after != 1 {
+		t.Fatalf("expected the second OnClose callback to run")
+	}
+
+	// the Chan is not wedged: later events are still delivered.
+	two := 2
+	c.ReadAndReset(&two)
+	c.Close()
+	if after != 2 {
+		t.Fatalf("expected OnClose to run on the second close, got %v", after)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(recovered)
+		mu.Unlock()
+		if n == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 4 recovered panics, got %v", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSpawnedCallbackPanicRecovered(t *testing.T) {
+	recovered := make(chan any, 4)
+	loquet.OnCallbackPanic = func(r any) { recovered <- r }
+	defer func() { loquet.OnCallbackPanic = nil }()
+
+	src := loquet.NewChan[int](nil)
+	filtered := loquet.Filter(src, func(*int) bool { panic("pred") })
+	flat := loquet.FlatMap(src, func(*int) *loquet.Chan[int] { panic("fn") })
+	scalars, cancel := loquet.WatchDerivedScalar(src, func(*int) float64 { panic("compute") })
+	defer func() {
+		cancel()
+		for range scalars {
+			// wait for the watcher to exit, before
+			// OnCallbackPanic is reset.
+		}
+	}()
+
+	reload := make(chan struct{})
+	loads := 0
+	_, stop := loquet.NewConfigChan(func() (*int, error) {
+		loads++
+		if loads == 1 {
+			return nil, nil // the initial load.
+		}
+		panic("load")
+	}, reload, nil)
+	defer stop()
+	reload <- struct{}{}
+
+	src.Close()
+	want := map[any]bool{"pred": true, "fn": true, "compute": true, "load": true}
+	for len(want) > 0 {
+		select {
+		case r := <-recovered:
+			delete(want, r)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("expected panics to be reported, still missing %v", want)
+		}
+	}
+	if filtered.Closed() || flat.Closed() {
+		t.Fatalf("expected a panic to leave the derived Chans open")
+	}
+}
@@ -0,0 +1,61 @@
+package loquet
+
+// WaitClosePropagated blocks until every derivation
+// waiter watching f has exited. Derivations are the
+// Chans made from f by MergeFirst, MergeAll, Filter,
+// Zip, Tee, FlatMap, Child and the like, each of which has a
+// waiter goroutine that closes the derived Chan once f
+// closes.
+//
+// The guarantee that makes this useful is that each
+// waiter closes (or decides not to close) its derived
+// Chan synchronously, before it exits. So once f
+// has closed and WaitClosePropagated returns, every
+// derived Chan that will ever close because of this
+// close of f already has, and its own derivations
+// may be waited on in turn. This turns the otherwise
+// racy timing of derived closes into something
+// a test can rely on:
+//
+//	src.CloseWith(&v)
+//	src.WaitClosePropagated()
+//	// a and b from Tee(src) are now closed.
+//
+// WaitClosePropagated returns at once if no waiter
+// is pending. Called on an open Chan, it blocks
+// until the waiters exit for another reason, if
+// ever; and a waiter that needs several sources, like
+// MergeAll's, exits only when all of them have closed.
+func (f *Chan[T]) WaitClosePropagated() {
+	f.mut.Lock()
+	if f.derivers == 0 {
+		f.mut.Unlock()
+		return
+	}
+	if f.propagated == nil {
+		f.propagated = make(chan struct{})
+	}
+	propagated := f.propagated
+	f.mut.Unlock()
+	<-propagated
+}
+
+// deriveAdd registers a derivation waiter
+// watching f; see WaitClosePropagated.
+func (f *Chan[T]) deriveAdd() {
+	f.mut.Lock()
+	f.derivers++
+	f.mut.Unlock()
+}
+
+// deriveDone notes that a waiter registered
+// by deriveAdd has exited.
+func (f *Chan[T]) deriveDone() {
+	f.mut.Lock()
+	f.derivers--
+	if f.derivers == 0 && f.propagated != nil {
+		close(f.propagated)
+		f.propagated = nil
+	}
+	f.mut.Unlock()
+}
@@ -0,0 +1,33 @@
+package loquet_test
+
+import (
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestWaitClosePropagated(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		src := loquet.NewChan[int](nil)
+		a, b := loquet.Tee(src)
+		child := src.Child()
+		grandchild := child.Child()
+		first := loquet.MergeFirst(src)
+
+		v := i
+		src.CloseWith(&v)
+		src.WaitClosePropagated()
+		for _, c := range []*loquet.Chan[int]{a, b, child, first} {
+			if val, isClosed := c.Read(); !isClosed || *val != i {
+				t.Fatalf("expected each derived Chan closed with %v", i)
+			}
+		}
+		child.WaitClosePropagated()
+		if _, isClosed := grandchild.Read(); !isClosed {
+			t.Fatalf("expected the grandchild closed after child propagated")
+		}
+	}
+
+	// nothing to wait for.
+	loquet.NewChan[int](nil).WaitClosePropagated()
+}
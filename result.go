@@ -0,0 +1,40 @@
+package loquet
+
+// Result codifies the extremely common value-or-error
+// pattern for a closeVal: a job either succeeds with
+// a Value, or fails with an Err.
+type Result[T any] struct {
+	Value *T
+	Err   error
+}
+
+// ResultChan is a Chan whose closeVal is a Result.
+// Since Go does not allow methods on a particular
+// instantiation such as Chan[Result[T]], ResultChan
+// embeds the *Chan, adding the Ok and Fail methods
+// while keeping all of the Chan methods available.
+// Use the embedded Chan field wherever a
+// *Chan[Result[T]] is needed.
+type ResultChan[T any] struct {
+	*Chan[Result[T]]
+}
+
+// NewResultChan returns a new, open ResultChan
+// with a nil closeVal.
+func NewResultChan[T any](opts ...Option[Result[T]]) *ResultChan[T] {
+	return &ResultChan[T]{Chan: NewChan[Result[T]](nil, opts...)}
+}
+
+// Ok closes the Chan with a successful Result carrying v.
+// Like CloseWith, it returns ErrAlreadyClosed, and
+// changes nothing, if the Chan is already closed.
+func (r *ResultChan[T]) Ok(v *T) error {
+	return r.CloseWith(&Result[T]{Value: v})
+}
+
+// Fail closes the Chan with a failed Result carrying err.
+// Like CloseWith, it returns ErrAlreadyClosed, and
+// changes nothing, if the Chan is already closed.
+func (r *ResultChan[T]) Fail(err error) error {
+	return r.CloseWith(&Result[T]{Err: err})
+}
@@ -0,0 +1,34 @@
+package loquet_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestResultChanOk(t *testing.T) {
+	rc := loquet.NewResultChan[int]()
+	n := 7
+	if err := rc.Ok(&n); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := rc.Fail(fmt.Errorf("too late")); err != loquet.ErrAlreadyClosed {
+		t.Fatalf("expected ErrAlreadyClosed, got %v", err)
+	}
+	res, isClosed := rc.Read()
+	if !isClosed || res.Err != nil || *res.Value != 7 {
+		t.Fatalf("expected a closed Ok result of 7, got %#v", res)
+	}
+}
+
+func TestResultChanFail(t *testing.T) {
+	rc := loquet.NewResultChan[int]()
+	boom := fmt.Errorf("boom")
+	rc.Fail(boom)
+	<-rc.WhenClosed()
+	res, _ := rc.Read()
+	if res.Err != boom || res.Value != nil {
+		t.Fatalf("expected a failed result, got %#v", res)
+	}
+}
@@ -0,0 +1,46 @@
+package loquet
+
+import (
+	"sync/atomic"
+)
+
+// Sequence is a source of globally increasing sequence
+// numbers that a fleet of Chans can share; see
+// WithSequence. (It is not a Clock, which tells time.)
+// The zero value is ready to use, and starts at 0,
+// so that the first number drawn is 1. A Sequence
+// must not be copied after first use.
+type Sequence struct {
+	n atomic.Int64
+}
+
+// Next draws the next sequence number.
+func (s *Sequence) Next() int64 {
+	return s.n.Add(1)
+}
+
+// Current returns the last sequence number
+// drawn, without drawing another.
+func (s *Sequence) Current() int64 {
+	return s.n.Load()
+}
+
+// WithSequence makes the Chan take its version numbers
+// from seq, which may be shared with other Chans. Every
+// recorded operation on the Chan, closes included, then
+// draws the next number from seq as the new version,
+// so that the versions reported by ReadState (and
+// ReadSince, Version and so on) give the total order
+// of events across all of the Chans sharing seq.
+// This is invaluable for reconstructing how a
+// close on one Chan raced with sets on another.
+//
+// Versions so drawn still increase on each Chan, as
+// ReadSince requires, but are no longer consecutive.
+// Note that, unlike the default, a plain Close
+// advances the version too.
+func WithSequence[T any](seq *Sequence) Option[T] {
+	return func(f *Chan[T]) {
+		f.seq = seq
+	}
+}
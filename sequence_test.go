@@ -0,0 +1,26 @@
+package loquet_test
+
+import (
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestSequenceOrdersAcrossChans(t *testing.T) {
+	var seq loquet.Sequence
+	a := loquet.NewChan[int](nil, loquet.WithSequence[int](&seq))
+	b := loquet.NewChan[int](nil, loquet.WithSequence[int](&seq))
+
+	one := 1
+	a.Set(&one)
+	b.Set(&one)
+	a.Close() // draws a number too.
+
+	va, vb := a.ReadState().Version, b.ReadState().Version
+	if vb != 2 || va != 3 || seq.Current() != 3 {
+		t.Fatalf("expected the total order b=2 < a=3, got a=%v b=%v", va, vb)
+	}
+	if _, _, _, changed := a.ReadSince(1); !changed {
+		t.Fatalf("expected ReadSince to see the sparse versions advance")
+	}
+}
@@ -0,0 +1,85 @@
+package loquet
+
+// SpanRecorder is the minimal subset of a tracing
+// span that a Chan needs in order to record span
+// events. It lets a Chan integrate with OpenTelemetry
+// (or any other tracer) without this package
+// importing it. Users adapt their span with a
+// small wrapper, for example
+//
+//	type otelSpan struct{ trace.Span }
+//
+//	func (s otelSpan) AddEvent(name string, attrs map[string]any) {
+//	    // convert attrs to []attribute.KeyValue ...
+//	    s.Span.AddEvent(name, trace.WithAttributes(kvs...))
+//	}
+type SpanRecorder interface {
+	AddEvent(name string, attrs map[string]any)
+}
+
+// spanHook holds the SpanRecorder configuration of a Chan.
+type spanHook[T any] struct {
+	rec     SpanRecorder
+	withSet bool
+}
+
+// record adds a span event for ev, if it is of a
+// kind that the hook records.
+func (h *spanHook[T]) record(ev event[T]) {
+	if h.rec == nil {
+		return
+	}
+	var name string
+	switch ev.kind {
+	case kindClose:
+		name = "loquet.close"
+	case kindSet:
+		if !h.withSet {
+			return
+		}
+		name = "loquet.set"
+	default:
+		return
+	}
+	h.rec.AddEvent(name, map[string]any{
+		"loquet.op":            ev.op,
+		"loquet.version":       ev.version,
+		"loquet.value_present": ev.new != nil,
+	})
+}
+
+// WithSpanRecorder arranges for the Chan to record
+// a "loquet.close" span event on rec when the Chan
+// closes. The event carries the attributes
+// "loquet.op" (the closing method, e.g. "CloseWith"),
+// "loquet.version" (an int64), and
+// "loquet.value_present" (a bool, true if
+// the closeVal is not nil).
+//
+// To also record a "loquet.set" event, with the same
+// attributes, each time the closeVal is Set, add
+// the WithSpanSetEvents option too.
+//
+// Like WithAuditLog, events are recorded after
+// the Chan's mutex has been released.
+func WithSpanRecorder[T any](rec SpanRecorder) Option[T] {
+	return func(f *Chan[T]) {
+		if f.span == nil {
+			f.span = &spanHook[T]{}
+		}
+		f.span.rec = rec
+	}
+}
+
+// WithSpanSetEvents extends WithSpanRecorder to record
+// a "loquet.set" span event on every Set, not
+// just on close. It has no effect without
+// WithSpanRecorder.
+func WithSpanSetEvents[T any]() Option[T] {
+	return func(f *Chan[T]) {
+		if f.span == nil {
+			f.span = &spanHook[T]{}
+		}
+		f.span.withSet = true
+	}
+}
@@ -0,0 +1,58 @@
+package loquet_test
+
+import (
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+type spanEvent struct {
+	name  string
+	attrs map[string]any
+}
+
+type fakeSpan struct {
+	events []spanEvent
+}
+
+func (s *fakeSpan) AddEvent(name string, attrs map[string]any) {
+	s.events = append(s.events, spanEvent{name: name, attrs: attrs})
+}
+
+func TestSpanRecorderClose(t *testing.T) {
+	span := &fakeSpan{}
+	c := loquet.NewChan[int](nil, loquet.WithSpanRecorder[int](span))
+	one := 1
+	c.Set(&one) // not recorded without WithSpanSetEvents.
+	c.Close()
+	c.Close()
+
+	if len(span.events) != 1 {
+		t.Fatalf("expected exactly one span event, got %#v", span.events)
+	}
+	ev := span.events[0]
+	if ev.name != "loquet.close" {
+		t.Fatalf("expected loquet.close event, got %v", ev.name)
+	}
+	if ev.attrs["loquet.version"] != int64(1) ||
+		ev.attrs["loquet.value_present"] != true ||
+		ev.attrs["loquet.op"] != "Close" {
+		t.Fatalf("unexpected attributes %#v", ev.attrs)
+	}
+}
+
+func TestSpanRecorderSets(t *testing.T) {
+	span := &fakeSpan{}
+	c := loquet.NewChan[int](nil,
+		loquet.WithSpanRecorder[int](span),
+		loquet.WithSpanSetEvents[int]())
+	c.Set(nil)
+	c.CloseWith(nil)
+
+	if len(span.events) != 2 || span.events[0].name != "loquet.set" {
+		t.Fatalf("expected a set then a close event, got %#v", span.events)
+	}
+	if span.events[1].attrs["loquet.value_present"] != false {
+		t.Fatalf("expected value_present false for a nil closeVal")
+	}
+}
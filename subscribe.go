@@ -0,0 +1,380 @@
+package loquet
+
+import "slices"
+
+// subscriber is one registration from Subscribe().
+type subscriber[T any] struct {
+	ch chan *T
+}
+
+// offer delivers val to the subscriber without
+// blocking. If the subscriber has not yet
+// received an earlier value, val is dropped.
+// offer reports whether val was delivered.
+func (s *subscriber[T]) offer(val *T) bool {
+	select {
+	case s.ch <- val:
+		return true
+	default:
+		return false
+	}
+}
+
+// replace delivers val to the subscriber without
+// blocking, first discarding any earlier value
+// that the subscriber has not yet received.
+func (s *subscriber[T]) replace(val *T) bool {
+	select {
+	case <-s.ch:
+	default:
+	}
+	return s.offer(val)
+}
+
+// WithFinalValuePriority makes a close jump the queue
+// for subscribers. By default, a subscriber that is
+// behind, still holding an undelivered value from an
+// earlier close, misses the new close. With this
+// option, the stale buffered value is instead
+// dropped, and the final value of the new close
+// is delivered in its place, so slow subscribers
+// always catch up to the latest close promptly.
+//
+// The cost is that a slow subscriber may never
+// see some earlier closes at all.
+//
+// The option applies to SubscribeBuffered too: on
+// each close, a subscriber's undelivered values are
+// dropped, and the final value takes their place,
+// whatever the OverflowPolicy. A close then never
+// waits behind, nor is dropped in favor of, the
+// stale values of a slow subscriber.
+func WithFinalValuePriority[T any]() Option[T] {
+	return func(f *Chan[T]) {
+		f.finalPriority = true
+	}
+}
+
+// Subscribe registers a durable subscriber, returning
+// a channel ch that receives the closeVal each time
+// the Chan transitions to closed. Unlike WhenClosed(),
+// which fires once per close and must be re-fetched
+// after a reset, ch survives across reset/reopen
+// cycles and fires on each close, making the Chan
+// a broadcast primitive for long-lived consumers.
+//
+// So that a slow subscriber can never block
+// Close, ch has a buffer of size 1, and a close
+// is dropped for a subscriber that has not yet
+// received the value from a previous close.
+// A subscriber only ever misses a close if it
+// was already behind. See WithFinalValuePriority
+// to drop the stale value instead.
+//
+// If the Chan is already closed when Subscribe
+// is called, nothing is sent for that existing
+// close; use Read() to observe the current state.
+//
+// The cancel func unregisters the subscriber
+// and closes ch. It is safe to call more than once.
+func (f *Chan[T]) Subscribe() (ch <-chan *T, cancel func()) {
+	sub := &subscriber[T]{
+		ch: make(chan *T, 1),
+	}
+	f.mut.Lock()
+	if f.subs == nil {
+		f.subs = make(map[*subscriber[T]]struct{})
+	}
+	f.subs[sub] = struct{}{}
+	f.mut.Unlock()
+
+	cancel = func() {
+		f.mut.Lock()
+		defer f.mut.Unlock()
+		if _, ok := f.subs[sub]; !ok {
+			return
+		}
+		delete(f.subs, sub)
+		close(sub.ch)
+	}
+	return sub.ch, cancel
+}
+
+// OverflowPolicy decides what SubscribeBuffered does
+// when a subscriber's buffer is full; see WithOverflowPolicy.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for the subscriber to make
+	// room, so that no value is ever missed. This is
+	// the default.
+	OverflowBlock OverflowPolicy = iota
+
+	// OverflowDropOldest discards the oldest undelivered
+	// value to make room for the new one.
+	OverflowDropOldest
+
+	// OverflowDropNewest discards the new value,
+	// keeping those already buffered.
+	OverflowDropNewest
+)
+
+// WithOverflowPolicy sets the policy applied when a
+// subscriber from SubscribeBuffered falls n values
+// behind. OverflowBlock, the default, favors a
+// complete event stream; the two drop policies
+// favor the liveness of the setters instead.
+func WithOverflowPolicy[T any](policy OverflowPolicy) Option[T] {
+	return func(f *Chan[T]) {
+		f.overflow = policy
+	}
+}
+
+// bufferedSub is one registration from SubscribeBuffered().
+type bufferedSub[T any] struct {
+	ch chan *T
+
+	// done is closed by cancel, releasing
+	// any delivery blocked on ch.
+	done chan struct{}
+}
+
+// send delivers val according to policy.
+// f.mut must not be held.
+func (s *bufferedSub[T]) send(val *T, policy OverflowPolicy) {
+	switch policy {
+	case OverflowDropOldest:
+		for {
+			select {
+			case s.ch <- val:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+			default:
+			}
+		}
+	case OverflowDropNewest:
+		select {
+		case s.ch <- val:
+		default:
+		}
+	default:
+		select {
+		case s.ch <- val:
+		case <-s.done:
+		}
+	}
+}
+
+// replace drops any undelivered values, and
+// delivers val in their place; see
+// WithFinalValuePriority. f.mut must not be held.
+func (s *bufferedSub[T]) replace(val *T) {
+	for {
+		select {
+		case <-s.ch:
+			continue
+		default:
+		}
+		select {
+		case s.ch <- val:
+			return
+		case <-s.done:
+			return
+		default:
+		}
+	}
+}
+
+// SubscribeBuffered registers a subscriber that
+// receives the closeVal after every Set and
+// every close, in order, on a channel ch of
+// capacity n. Where Subscribe reports only
+// closes, and drops those a slow subscriber
+// is not ready for, SubscribeBuffered aims
+// to deliver the whole stream of updates.
+//
+// Values are delivered after the Chan's mutex
+// has been released, by the goroutine that made
+// the change. Under the default OverflowBlock
+// policy, a setter therefore blocks once a
+// subscriber is n values behind, until that
+// subscriber catches up or cancels. Beware: a
+// subscriber that stops receiving without calling
+// cancel will stall that setter forever, and with it the
+// delivery of all later events on the Chan, including
+// those for audit logs, spans and transition hooks.
+// Use WithOverflowPolicy to drop values instead
+// when liveness matters more than completeness,
+// and WithFinalValuePriority to put each close
+// ahead of any values still undelivered.
+//
+// Nothing is sent for the state of the Chan at
+// the time of the call; use Read() for that.
+// n must be at least 1.
+//
+// The cancel func unregisters the subscriber,
+// releases any blocked setter, and closes ch once
+// no further delivery to it can be in flight.
+// It is safe to call more than once.
+func (f *Chan[T]) SubscribeBuffered(n int) (ch <-chan *T, cancel func()) {
+	if n < 1 {
+		panic("loquet: SubscribeBuffered needs n >= 1")
+	}
+	sub := &bufferedSub[T]{
+		ch:   make(chan *T, n),
+		done: make(chan struct{}),
+	}
+	f.mut.Lock()
+	f.bsubs = append(f.bsubs, sub)
+	f.mut.Unlock()
+
+	cancel = func() {
+		f.mut.Lock()
+		defer f.unlock()
+		i := slices.Index(f.bsubs, sub)
+		if i < 0 {
+			return
+		}
+		f.bsubs = slices.Delete(f.bsubs, i, i+1)
+		close(sub.done)
+		// Close ch from the event queue, after every
+		// earlier delivery to sub has completed.
+		f.pending = append(f.pending, event[T]{
+			kind:  kindUnsubscribe,
+			unsub: sub,
+		})
+	}
+	return sub.ch, cancel
+}
+
+// matcher is one registration from WhenMatches().
+type matcher[T any] struct {
+	pred func(*T) bool
+	ch   chan *T
+
+	// fired is only accessed by the
+	// goroutine delivering events.
+	fired bool
+}
+
+// WhenMatches returns a channel that receives the
+// first closeVal satisfying pred, whether installed
+// by a Set, a close or a reset. If the current closeVal
+// already matches, it is sent at once. This is the
+// select-friendly counterpart to the blocking WaitUntil:
+//
+//	select {
+//	case v := <-status.WhenMatches(isReady):
+//	    ... use v ...
+//	case <-ctx.Done():
+//	}
+//
+// Internally a one-shot subscriber is registered, and
+// pred is evaluated on the current closeVal and then
+// after each update, until it first returns true.
+// No goroutine is spent waiting. pred is called outside
+// the Chan's mutex, by the goroutine that made the
+// update, one call at a time, and so need not be
+// safe for concurrent use. No update made after
+// WhenMatches returns is skipped.
+//
+// The channel receives at most one value and is never
+// closed. A close that does not match pred is not
+// reported; WhenMatches then keeps waiting through any
+// later resets. Since there is no way to cancel
+// the registration, a pred that never matches holds
+// a little memory for the life of the Chan.
+func (f *Chan[T]) WhenMatches(pred func(*T) bool) <-chan *T {
+	m := &matcher[T]{
+		pred: pred,
+		ch:   make(chan *T, 1),
+	}
+	f.mut.Lock()
+	defer f.unlock()
+	f.matchers = append(f.matchers, m)
+	// evaluate the current value in order with later updates.
+	f.pending = append(f.pending, event[T]{
+		kind:     kindMatch,
+		new:      f.closeVal,
+		matchers: []*matcher[T]{m},
+	})
+	return m.ch
+}
+
+// match tries each of the matchers on val,
+// unregistering those that fire. f.mut
+// must not be held.
+func (f *Chan[T]) match(matchers []*matcher[T], val *T) {
+	for _, m := range matchers {
+		if m.fired {
+			continue
+		}
+		var ok bool
+		protect(func() { ok = m.pred(val) })
+		if !ok {
+			continue
+		}
+		m.fired = true
+		m.ch <- val
+		f.mut.Lock()
+		if i := slices.Index(f.matchers, m); i >= 0 {
+			f.matchers = slices.Delete(f.matchers, i, i+1)
+		}
+		f.mut.Unlock()
+	}
+}
+
+// WithTargets registers plain Go channels that each
+// receive the closeVal every time the Chan closes.
+// This is like PipeTo for a set of subscribers known
+// at construction, but spends no goroutine per target:
+// the sends are made in the close path, by the goroutine
+// that closed the Chan, after its mutex has been released.
+//
+// By default the sends do not block: a target with no
+// room (no waiting receiver, and a full buffer) misses
+// that close. See WithTargetPolicy to block instead.
+// The targets are never closed by the Chan.
+// The option may be given more than once.
+func WithTargets[T any](targets ...chan<- *T) Option[T] {
+	return func(f *Chan[T]) {
+		f.targets = append(f.targets, targets...)
+	}
+}
+
+// WithTargetPolicy sets the policy for sends to the
+// WithTargets channels. OverflowDropNewest, the default,
+// never blocks the closer. OverflowBlock waits until
+// each target in turn accepts the closeVal, which
+// guarantees delivery but lets one stalled target
+// hold up the closer, and all later event delivery
+// on the Chan, indefinitely. OverflowDropOldest cannot
+// apply to a send-only channel, and is treated
+// as OverflowDropNewest.
+func WithTargetPolicy[T any](policy OverflowPolicy) Option[T] {
+	return func(f *Chan[T]) {
+		f.targetPolicy = policy
+	}
+}
+
+// sendTargets sends val to each of the WithTargets
+// channels. A send to a target that the user has
+// closed panics, and is recovered as for callbacks;
+// see OnCallbackPanic. f.mut must not be held.
+func (f *Chan[T]) sendTargets(val *T) {
+	for _, target := range f.targets {
+		protect(func() {
+			if f.targetPolicy == OverflowBlock {
+				target <- val
+				return
+			}
+			select {
+			case target <- val:
+			default:
+			}
+		})
+	}
+}
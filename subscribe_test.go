@@ -0,0 +1,264 @@
+package loquet_test
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestSubscribeAcrossResets(t *testing.T) {
+	one, two := 1, 2
+	c := loquet.NewChan[int](nil)
+	ch, cancel := c.Subscribe()
+
+	c.CloseWith(&one)
+	if got := <-ch; *got != 1 {
+		t.Fatalf("expected 1 on first close, got %v", *got)
+	}
+
+	// reset and close again: must not panic, and
+	// the subscriber hears the second close too.
+	c.ReadAndReset(nil)
+	c.CloseWith(&two)
+	if got := <-ch; *got != 2 {
+		t.Fatalf("expected 2 on second close, got %v", *got)
+	}
+	select {
+	case <-c.WhenClosed():
+	default:
+		t.Fatalf("WhenClosed should be closed after the second close")
+	}
+
+	cancel()
+	cancel() // idempotent
+	if _, ok := <-ch; ok {
+		t.Fatalf("expected ch to be closed after cancel")
+	}
+}
+
+func TestSubscribeDropsWhenBehind(t *testing.T) {
+	one, two := 1, 2
+	c := loquet.NewChan[int](nil)
+	ch, cancel := c.Subscribe()
+	defer cancel()
+
+	c.CloseWith(&one)
+	c.ReadAndReset(nil)
+	c.CloseWith(&two) // subscriber is behind, dropped.
+
+	if got := <-ch; *got != 1 {
+		t.Fatalf("expected 1, got %v", *got)
+	}
+	select {
+	case got := <-ch:
+		t.Fatalf("expected the second close to be dropped, got %v", *got)
+	default:
+	}
+}
+
+func TestSubscribeFinalValuePriority(t *testing.T) {
+	one, two := 1, 2
+	c := loquet.NewChan[int](nil, loquet.WithFinalValuePriority[int]())
+	ch, cancel := c.Subscribe()
+	defer cancel()
+
+	c.CloseWith(&one)
+	c.ReadAndReset(nil)
+	c.CloseWith(&two) // subscriber is behind; stale 1 is dropped.
+
+	if got := <-ch; *got != 2 {
+		t.Fatalf("expected the final value 2 to jump the queue, got %v", *got)
+	}
+	select {
+	case got := <-ch:
+		t.Fatalf("expected the stale value to be dropped, got %v", *got)
+	default:
+	}
+}
+
+func TestSubscribeBufferedSeesEveryUpdate(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	ch, cancel := c.SubscribeBuffered(1)
+
+	// the default policy blocks the setter
+	// until the subscriber catches up.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 1; i <= 5; i++ {
+			c.Set(&i)
+		}
+		c.Close()
+	}()
+	for want := 1; want <= 5; want++ {
+		if got := <-ch; *got != want {
+			t.Fatalf("expected %v, got %v", want, *got)
+		}
+	}
+	if got := <-ch; *got != 5 {
+		t.Fatalf("expected the close to carry 5, got %v", *got)
+	}
+	<-done
+
+	cancel()
+	if _, ok := <-ch; ok {
+		t.Fatalf("expected ch to be closed by cancel")
+	}
+	cancel() // idempotent
+}
+
+func TestSubscribeBufferedCancelReleasesSetter(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	_, cancel := c.SubscribeBuffered(1)
+
+	one, two := 1, 2
+	c.Set(&one) // fills the buffer.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Set(&two) // blocks: nobody is receiving.
+	}()
+	select {
+	case <-done:
+		t.Fatalf("expected Set to block on the full subscriber")
+	case <-time.After(20 * time.Millisecond):
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected cancel to release the blocked Set")
+	}
+}
+
+func TestSubscribeBufferedOverflowPolicies(t *testing.T) {
+	for _, tc := range []struct {
+		policy loquet.OverflowPolicy
+		want   []int
+	}{
+		{loquet.OverflowDropOldest, []int{4, 5}},
+		{loquet.OverflowDropNewest, []int{1, 2}},
+	} {
+		c := loquet.NewChan[int](nil, loquet.WithOverflowPolicy[int](tc.policy))
+		ch, cancel := c.SubscribeBuffered(2)
+		for i := 1; i <= 5; i++ {
+			c.Set(&i) // never blocks.
+		}
+		cancel()
+		var got []int
+		for v := range ch {
+			got = append(got, *v)
+		}
+		if !slices.Equal(got, tc.want) {
+			t.Fatalf("policy %v: expected %v, got %v", tc.policy, tc.want, got)
+		}
+	}
+}
+
+func TestSubscribeBufferedFinalValuePriority(t *testing.T) {
+	for _, policy := range []loquet.OverflowPolicy{
+		loquet.OverflowBlock,
+		loquet.OverflowDropOldest,
+		loquet.OverflowDropNewest,
+	} {
+		c := loquet.NewChan[int](nil,
+			loquet.WithOverflowPolicy[int](policy),
+			loquet.WithFinalValuePriority[int](),
+		)
+		ch, cancel := c.SubscribeBuffered(2)
+		one, two, final := 1, 2, 42
+		c.Set(&one)
+		c.Set(&two) // fills the buffer.
+		c.CloseWith(&final)
+		if n := len(ch); n != 1 {
+			t.Fatalf("policy %v: expected the stale values dropped, got %v buffered", policy, n)
+		}
+		if got := <-ch; got != &final {
+			t.Fatalf("policy %v: expected the final value, got %v", policy, *got)
+		}
+		cancel()
+	}
+}
+
+func TestSubscribeBufferedMarkClosed(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	ch, cancel := c.SubscribeBuffered(4)
+	defer cancel()
+
+	one := 1
+	c.Set(&one)
+	c.MarkClosed()
+	if n := len(ch); n != 1 {
+		t.Fatalf("expected MarkClosed to notify nobody, got %v buffered", n)
+	}
+	c.Close()
+	if n := len(ch); n != 2 {
+		t.Fatalf("expected Close to send the closeVal once, got %v buffered", n)
+	}
+}
+
+func TestWhenMatches(t *testing.T) {
+	isBig := func(v *int) bool { return v != nil && *v > 10 }
+	c := loquet.NewChan[int](nil)
+	ch := c.WhenMatches(isBig)
+
+	five, twenty, thirty := 5, 20, 30
+	c.Set(&five)
+	select {
+	case v := <-ch:
+		t.Fatalf("expected no match yet, got %v", *v)
+	default:
+	}
+	c.Set(&twenty)
+	c.CloseWith(&thirty) // only the first match is sent.
+	select {
+	case v := <-ch:
+		if *v != 20 {
+			t.Fatalf("expected the first match 20, got %v", *v)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected a match")
+	}
+
+	// the current value is checked at registration.
+	select {
+	case v := <-c.WhenMatches(isBig):
+		if *v != 30 {
+			t.Fatalf("expected the current value 30, got %v", *v)
+		}
+	default:
+		t.Fatalf("expected an immediate match on the current value")
+	}
+}
+
+func TestWithTargets(t *testing.T) {
+	a := make(chan *int, 1)
+	b := make(chan *int) // unbuffered, nobody receiving: misses the close.
+	c := loquet.NewChan[int](nil, loquet.WithTargets[int](a, b))
+	one := 1
+	c.CloseWith(&one)
+	select {
+	case v := <-a:
+		if v != &one {
+			t.Fatalf("expected the closeVal on target a")
+		}
+	default:
+		t.Fatalf("expected target a to receive the close")
+	}
+
+	// OverflowBlock waits for the receiver.
+	blocking := loquet.NewChan[int](nil,
+		loquet.WithTargets[int](b),
+		loquet.WithTargetPolicy[int](loquet.OverflowBlock))
+	go blocking.CloseWith(&one)
+	select {
+	case v := <-b:
+		if v != &one {
+			t.Fatalf("expected the closeVal on target b")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected a blocking send to target b")
+	}
+}
@@ -0,0 +1,13 @@
+// Package copychan deliberately copies a loquet.Chan
+// by value, so that TestVetCatchesChanCopy can check
+// that go vet reports it.
+package copychan
+
+import "github.com/glycerine/loquet"
+
+func byValue(c loquet.Chan[int]) {}
+
+func Copy() {
+	c := loquet.NewChan[int](nil)
+	byValue(*c)
+}
@@ -0,0 +1,32 @@
+package loquet
+
+import (
+	"sync/atomic"
+)
+
+// NewThresholdChan returns a Chan that closes once an
+// accumulated total reaches threshold, along with
+// the add func that accumulates into that total.
+//
+// Each call to add(delta) adds delta to the running
+// total. The first add that brings the total to or
+// past threshold closes the Chan with finalVal(total),
+// where total is the running total just after that
+// add. Later adds still accumulate, but since the
+// Chan is already closed they have no further effect
+// on it. Negative deltas are allowed.
+//
+// add is safe to call from many goroutines at once.
+// finalVal is called at most once.
+func NewThresholdChan[T any](threshold int64, finalVal func(total int64) *T) (f *Chan[T], add func(delta int64)) {
+	f = NewChan[T](nil)
+	var total atomic.Int64
+	var fired atomic.Bool
+	add = func(delta int64) {
+		sum := total.Add(delta)
+		if sum >= threshold && fired.CompareAndSwap(false, true) {
+			f.CloseWith(finalVal(sum))
+		}
+	}
+	return
+}
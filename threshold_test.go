@@ -0,0 +1,28 @@
+package loquet_test
+
+import (
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestThresholdChan(t *testing.T) {
+	c, add := loquet.NewThresholdChan(10, func(total int64) *int64 {
+		return &total
+	})
+
+	add(3)
+	add(4)
+	if _, isClosed := c.Read(); isClosed {
+		t.Fatalf("should still be open below the threshold")
+	}
+	add(5) // crosses 10 at 12.
+	val, isClosed := c.Read()
+	if !isClosed || *val != 12 {
+		t.Fatalf("expected closed with total 12, got %v, %v", val, isClosed)
+	}
+	add(100)
+	if val, _ := c.Read(); *val != 12 {
+		t.Fatalf("closeVal should not change after close, got %v", *val)
+	}
+}
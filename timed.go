@@ -0,0 +1,103 @@
+package loquet
+
+import (
+	"sync"
+	"time"
+)
+
+// CloseAt schedules the Chan to CloseWith(closeVal)
+// at time t. This makes it easy to implement a
+// per-operation timeout that still conveys a
+// meaningful timeout value to readers.
+//
+// If the Chan is closed by other means before t,
+// the timer is stopped and the scheduling goroutine
+// exits without touching the Chan. If the Chan is
+// already closed when CloseAt is called, nothing
+// is scheduled.
+//
+// The deadline is measured against the Chan's
+// Clock; see WithClock. A close made by CloseAt
+// records context.DeadlineExceeded as its cause;
+// see CauseChain.
+//
+// The returned cancel func aborts the scheduled
+// close if it has not yet happened. It is safe
+// to call more than once.
+func (f *Chan[T]) CloseAt(t time.Time, closeVal *T) (cancel func()) {
+	stop := make(chan struct{})
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() { close(stop) })
+	}
+
+	whenClosed := f.WhenClosed()
+	clock := f.getClock()
+	spawn(func() {
+		select {
+		case <-clock.After(t.Sub(clock.Now())):
+			f.closeWithCauses("CloseAt", closeVal, deadlineCauses)
+		case <-whenClosed:
+		case <-stop:
+		}
+	})
+	return
+}
+
+// WithTimeout schedules the Chan to CloseWith(timeoutVal)
+// after d, unless it is closed first, giving the
+// common "finish within d, or report a timeout" pattern
+// in one line:
+//
+//	cancel := status.WithTimeout(5*time.Second, &Message{Err: ErrTimeout})
+//	defer cancel()
+//	go doJob(status) // closes status on completion.
+//
+// Readers then see whichever came first, the job's
+// closeVal or timeoutVal. WithTimeout is CloseAt with
+// a deadline d from now on the Chan's Clock, and the
+// same rules apply: the returned cancel stops the
+// timer, and is safe to call more than once.
+func (f *Chan[T]) WithTimeout(d time.Duration, timeoutVal *T) (cancel func()) {
+	return f.CloseAt(f.getClock().Now().Add(d), timeoutVal)
+}
+
+// ClosedAt returns the time t at which the Chan
+// closed, and ok true, if the Chan is currently closed.
+// On an open Chan, it returns the zero time and
+// ok false. The time is taken from the Chan's
+// Clock (see WithClock) by whichever call closed
+// it: Close, CloseWith, CloseAt, MarkClosed and so on.
+// Later Close calls, which are no-ops, do not
+// move it, and a reset clears it.
+//
+// This supports latency analysis: a reader that
+// notes time.Since(t) right after waking from
+// WhenClosed learns how long it took to notice
+// the close, without instrumenting the closer.
+func (f *Chan[T]) ClosedAt() (t time.Time, ok bool) {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	return f.closedAt, f.isClosed
+}
+
+// OpenDuration returns how long the Chan stayed open
+// before its current close, with ok true, or zero and
+// false if it is open now. The Chan is open from
+// NewChan until its first close, and from each reset
+// of a closed Chan (by Reopen, ReadAndReset and
+// so on) until the next close. Both ends are
+// measured on the Chan's Clock.
+//
+// For a job that reports completion by closing a Chan
+// made just as it starts, this is the job's duration,
+// a common latency metric, without the callers
+// having to track start times themselves.
+func (f *Chan[T]) OpenDuration() (d time.Duration, ok bool) {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+	if !f.isClosed {
+		return 0, false
+	}
+	return f.closedAt.Sub(f.openedAt), true
+}
@@ -0,0 +1,109 @@
+package loquet_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestCloseAt(t *testing.T) {
+	timeout := &Message{Err: errTimeout}
+	c := loquet.NewChan[Message](nil)
+	c.CloseAt(time.Now().Add(10*time.Millisecond), timeout)
+
+	select {
+	case <-c.WhenClosed():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("CloseAt never closed the Chan")
+	}
+	if val, _ := c.Read(); val != timeout {
+		t.Fatalf("expected the timeout closeVal, got %#v", val)
+	}
+}
+
+func TestCloseAtCancelled(t *testing.T) {
+	c := loquet.NewChan[Message](nil)
+	cancel := c.CloseAt(time.Now().Add(10*time.Millisecond), &Message{Err: errTimeout})
+	cancel()
+	cancel()
+
+	time.Sleep(30 * time.Millisecond)
+	if _, isClosed := c.Read(); isClosed {
+		t.Fatalf("cancelled CloseAt should not close the Chan")
+	}
+}
+
+func TestCloseAtClosedFirst(t *testing.T) {
+	done := &Message{}
+	c := loquet.NewChan[Message](nil)
+	c.CloseAt(time.Now().Add(10*time.Millisecond), &Message{Err: errTimeout})
+	c.CloseWith(done)
+
+	time.Sleep(30 * time.Millisecond)
+	if val, _ := c.Read(); val != done {
+		t.Fatalf("expected the earlier closeVal to win, got %#v", val)
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	timeout := &Message{Err: errTimeout}
+	c := loquet.NewChan[Message](nil)
+	defer c.WithTimeout(10*time.Millisecond, timeout)()
+	waitClosed(t, c)
+	if val, _ := c.Read(); val != timeout {
+		t.Fatalf("expected the timeout closeVal, got %#v", val)
+	}
+
+	// completion wins over a later deadline.
+	done := &Message{}
+	c = loquet.NewChan[Message](nil)
+	cancel := c.WithTimeout(20*time.Millisecond, timeout)
+	c.CloseWith(done)
+	time.Sleep(40 * time.Millisecond)
+	cancel()
+	if val, _ := c.Read(); val != done {
+		t.Fatalf("expected the job's closeVal to win, got %#v", val)
+	}
+}
+
+func TestClosedAt(t *testing.T) {
+	clk := newFakeClock()
+	c := loquet.NewChan[int](nil, loquet.WithClock[int](clk))
+	if _, ok := c.ClosedAt(); ok {
+		t.Fatalf("expected ok false on an open Chan")
+	}
+	want := clk.Now()
+	c.Close()
+	clk.Advance(time.Second)
+	c.Close() // no-op: must not move the time.
+	got, ok := c.ClosedAt()
+	if !ok || !got.Equal(want) {
+		t.Fatalf("expected ClosedAt %v, true; got %v, %v", want, got, ok)
+	}
+	c.ReadAndReset(nil)
+	if got, ok := c.ClosedAt(); ok || !got.IsZero() {
+		t.Fatalf("expected a reset to clear ClosedAt, got %v, %v", got, ok)
+	}
+}
+
+func TestOpenDuration(t *testing.T) {
+	clk := newFakeClock()
+	c := loquet.NewChan[int](nil, loquet.WithClock[int](clk))
+	if _, ok := c.OpenDuration(); ok {
+		t.Fatalf("expected ok false on an open Chan")
+	}
+	clk.Advance(3 * time.Second)
+	c.Close()
+	if d, ok := c.OpenDuration(); !ok || d != 3*time.Second {
+		t.Fatalf("expected 3s, got %v, %v", d, ok)
+	}
+
+	clk.Advance(time.Hour) // time spent closed does not count.
+	c.ReadAndReset(nil)
+	clk.Advance(time.Second)
+	c.Close()
+	if d, ok := c.OpenDuration(); !ok || d != time.Second {
+		t.Fatalf("expected 1s since the reset, got %v, %v", d, ok)
+	}
+}
@@ -0,0 +1,62 @@
+package loquet
+
+import (
+	"sync/atomic"
+)
+
+// CloseToken lets one logical close span several Chans
+// with exactly-once semantics. Any number of Chans may
+// share a CloseToken; the first CloseWithToken
+// to present it, on whichever Chan, consumes the
+// token and performs its close. All later
+// CloseWithToken calls presenting the same token
+// do nothing.
+//
+// A CloseToken must be obtained from NewCloseToken,
+// and should be passed by pointer.
+type CloseToken struct {
+	consumed atomic.Bool
+}
+
+// NewCloseToken returns a fresh, unconsumed CloseToken.
+func NewCloseToken() *CloseToken {
+	return &CloseToken{}
+}
+
+// Consumed reports whether the token has been
+// used by a CloseWithToken.
+func (tok *CloseToken) Consumed() bool {
+	return tok.consumed.Load()
+}
+
+// CloseWithToken behaves like CloseWith, but only if
+// token has not already been consumed by any Chan.
+// The token is consumed only when a close actually
+// happens, so a CloseWithToken on an already
+// closed Chan leaves the token available.
+//
+// The returned error is ErrAlreadyClosed if
+// the Chan was already closed, or ErrTokenConsumed
+// if the token was already consumed. A nil error
+// means this call consumed the token, and
+// closeVal was stored and broadcast.
+func (f *Chan[T]) CloseWithToken(closeVal *T, token *CloseToken) error {
+	f.mut.Lock()
+	defer f.unlock()
+
+	if f.frozen {
+		return ErrFrozen
+	}
+	if f.isClosed {
+		return ErrAlreadyClosed
+	}
+	if !token.consumed.CompareAndSwap(false, true) {
+		return ErrTokenConsumed
+	}
+	old := f.closeVal
+	f.closeVal = closeVal
+	f.version++
+	f.closeLocked()
+	f.recordLocked(kindClose, "CloseWithToken", old)
+	return nil
+}
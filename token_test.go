@@ -0,0 +1,39 @@
+package loquet_test
+
+import (
+	"testing"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestCloseWithTokenSharedAcrossChans(t *testing.T) {
+	one, two := 1, 2
+	a := loquet.NewChan[int](nil)
+	b := loquet.NewChan[int](nil)
+	tok := loquet.NewCloseToken()
+
+	if err := a.CloseWithToken(&one, tok); err != nil {
+		t.Fatalf("expected first CloseWithToken to close, got %v", err)
+	}
+	if !tok.Consumed() {
+		t.Fatalf("expected token to be consumed")
+	}
+	if err := b.CloseWithToken(&two, tok); err != loquet.ErrTokenConsumed {
+		t.Fatalf("expected ErrTokenConsumed, got %v", err)
+	}
+	if val, isClosed := a.Read(); !isClosed || *val != 1 {
+		t.Fatalf("expected a closed with 1")
+	}
+	if val, isClosed := b.Read(); isClosed || val != nil {
+		t.Fatalf("expected b still open and unset")
+	}
+
+	// an already closed Chan does not consume a fresh token.
+	fresh := loquet.NewCloseToken()
+	if err := a.CloseWithToken(&two, fresh); err != loquet.ErrAlreadyClosed {
+		t.Fatalf("expected ErrAlreadyClosed, got %v", err)
+	}
+	if fresh.Consumed() {
+		t.Fatalf("token should not be consumed by a failed close")
+	}
+}
@@ -0,0 +1,475 @@
+package loquet
+
+import (
+	"context"
+	"iter"
+	"reflect"
+	"runtime"
+	"time"
+)
+
+// WaitForVariant waits until the closeVal satisfies
+// match, or the Chan closes, or ctx is done.
+// It is meant for a Chan whose closeVal is a tagged
+// union (or any other non-comparable type), where
+// one wants to wake only when a specific variant
+// appears; match is re-checked on each change.
+//
+// When match is satisfied, WaitForVariant returns
+// the matching closeVal, whether the Chan is closed
+// (in isClosed), and a nil error. A closeVal that
+// was present before the call will match immediately.
+//
+// If the Chan closes first, WaitForVariant returns
+// the final closeVal with isClosed true and a nil
+// error, even if match does not hold for it, so that
+// readers are never stuck waiting on a Chan
+// that will not change further. Callers that
+// need to distinguish the two should check
+// match(val) themselves.
+//
+// If ctx is done first, the current closeVal and
+// isClosed status are returned along with ctx.Err().
+//
+// match is called without the Chan's mutex held,
+// but may see a closeVal that is about to be replaced.
+func WaitForVariant[T any](ctx context.Context, c *Chan[T], match func(*T) bool) (val *T, isClosed bool, err error) {
+	c.waiting.Add(1)
+	defer c.waiting.Add(-1)
+	for {
+		var changed <-chan struct{}
+		val, isClosed, changed = c.readAndWatch()
+		if isClosed || match(val) {
+			return
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			val, isClosed = c.Read()
+			err = ctx.Err()
+			return
+		}
+	}
+}
+
+// WaitUntil waits for the closeVal to satisfy pred,
+// returning the first such closeVal, whether it was
+// established while the Chan was open (by NewChan
+// or a Set) or at close. A closeVal that satisfies
+// pred at the time of the call is returned immediately.
+//
+// WaitUntil also returns once the Chan closes, even
+// if pred was never satisfied, so that readers are
+// never stuck waiting forever on a Chan that will
+// not change further. In that case the final closeVal
+// is returned along with ErrClosedBeforeMatch.
+//
+// If ctx is done first, the current closeVal
+// is returned along with ctx.Err().
+//
+// Like WaitForVariant, WaitUntil wakes on each change
+// to the Chan, and calls pred without the mutex held.
+func (f *Chan[T]) WaitUntil(ctx context.Context, pred func(*T) bool) (*T, error) {
+	val, isClosed, err := WaitForVariant(ctx, f, pred)
+	if err != nil {
+		return val, err
+	}
+	if isClosed && !pred(val) {
+		return val, ErrClosedBeforeMatch
+	}
+	return val, nil
+}
+
+// WaitVersions waits for count changes to the closeVal,
+// counted from the time of the call, and then returns
+// the closeVal current at that point. This gives tests
+// of stateful producers a step-based synchronization:
+// "wait until three more updates have happened". Every
+// Set counts, as do CloseWith and the resets: each
+// operation that, by default, advances the version by
+// one. A plain Close does not count.
+// A count of zero or less returns at once.
+//
+// The changes are counted, rather than read off the
+// version, since with WithSequence the version may
+// jump by any amount at once, and a plain Close
+// advances it too.
+//
+// If the Chan is closed before enough changes (or is
+// already closed), the final closeVal is returned
+// with ErrClosedBeforeMatch, as WaitUntil does, since
+// there will be no further changes without a reset.
+// If ctx is done first, the current closeVal
+// is returned with ctx.Err().
+func (f *Chan[T]) WaitVersions(ctx context.Context, count int64) (*T, error) {
+	f.waiting.Add(1)
+	defer f.waiting.Add(-1)
+	s, updates, changed := f.watchUpdates()
+	target := updates + count
+	for {
+		if updates >= target {
+			return s.CloseVal, nil
+		}
+		if s.IsClosed {
+			return s.CloseVal, ErrClosedBeforeMatch
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			val, _ := f.Read()
+			return val, ctx.Err()
+		}
+		s, updates, changed = f.watchUpdates()
+	}
+}
+
+// WaitWithEscalation waits for the Chan to close, with
+// tiered timeouts that model "nudge, then abandon".
+// If the Chan is still open after t1, onWarn is called
+// once with the current closeVal. If it is still open
+// after t2, WaitWithEscalation gives up and returns
+// the current closeVal with context.DeadlineExceeded.
+// Both durations are measured from the call,
+// on the Chan's Clock; t1 should be less than t2.
+//
+// If the Chan closes first, the final closeVal is
+// returned with isClosed true and a nil error,
+// and onWarn is skipped if it has not yet fired.
+// If ctx is done first, the current closeVal is
+// returned with ctx.Err().
+//
+// onWarn is called on the waiting goroutine, so
+// the t2 deadline is not observed until it returns.
+// A nil onWarn is allowed.
+func (f *Chan[T]) WaitWithEscalation(ctx context.Context, t1 time.Duration, onWarn func(curVal *T), t2 time.Duration) (val *T, isClosed bool, err error) {
+	f.waiting.Add(1)
+	defer f.waiting.Add(-1)
+	clock := f.getClock()
+	warn := clock.After(t1)
+	giveUp := clock.After(t2)
+	whenClosed := f.WhenClosed()
+	for {
+		select {
+		case <-whenClosed:
+			val, isClosed = f.Read()
+			return
+		case <-warn:
+			warn = nil
+			if onWarn != nil {
+				cur, _ := f.Read()
+				onWarn(cur)
+			}
+		case <-giveUp:
+			val, isClosed = f.Read()
+			err = context.DeadlineExceeded
+			return
+		case <-ctx.Done():
+			val, isClosed = f.Read()
+			err = ctx.Err()
+			return
+		}
+	}
+}
+
+// CloseWithAndWait is like CloseWith, but after closing,
+// it blocks until at least one reader has observed
+// the close, or ctx is done. This suits handoffs where
+// the producer must not proceed until a consumer
+// has the value.
+//
+// A close is observed by a Read (or ReadSince, or a
+// reset) that finds the Chan closed, by one of the
+// waiting methods such as WaitUntil returning, or
+// by delivery of the closeVal to a subscriber
+// (see Subscribe). Merely selecting on WhenClosed()
+// does not count, since the Chan cannot see that.
+//
+// The returned error is ErrAlreadyClosed if the Chan
+// was already closed, in which case CloseWithAndWait
+// does not wait. If ctx is done before the close is
+// observed, ctx.Err() is returned; the Chan
+// nonetheless remains closed with closeVal.
+func (f *Chan[T]) CloseWithAndWait(ctx context.Context, closeVal *T) error {
+	f.mut.Lock()
+	if f.frozen {
+		f.mut.Unlock()
+		return ErrFrozen
+	}
+	if f.isClosed {
+		f.mut.Unlock()
+		return ErrAlreadyClosed
+	}
+	old := f.closeVal
+	f.closeVal = closeVal
+	f.version++
+	f.closeLocked()
+	f.recordLocked(kindClose, "CloseWithAndWait", old)
+	observed := make(chan struct{})
+	if f.observed.Load() {
+		close(observed)
+	} else {
+		f.observedCh = observed
+	}
+	f.unlock()
+
+	select {
+	case <-observed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WaitPoll waits for the Chan to close by polling
+// Read(), sleeping between polls with an exponential
+// backoff that starts at initial and doubles up to
+// max. It returns the final closeVal once the Chan
+// is closed, or the current closeVal with ctx.Err()
+// if ctx is done first.
+//
+// WhenClosed is the better way to wait, and should be
+// preferred. WaitPoll exists for environments, such as
+// some FFI and callback-driven bridges, that genuinely
+// cannot block on a Go channel, where a well-behaved
+// backoff poller beats a naive tight loop.
+// The sleeps are measured on the Chan's Clock.
+//
+// So that WaitPoll can never degenerate into that
+// tight loop, an initial below minPollDelay (a
+// millisecond) is raised to it, and a max below
+// initial is taken to be initial, so that the
+// delay then stays at initial.
+func (f *Chan[T]) WaitPoll(ctx context.Context, initial, max time.Duration) (*T, error) {
+	f.waiting.Add(1)
+	defer f.waiting.Add(-1)
+	clock := f.getClock()
+	initial = maxDuration(initial, minPollDelay)
+	max = maxDuration(max, initial)
+	delay := initial
+	for {
+		val, isClosed := f.Read()
+		if isClosed {
+			return val, nil
+		}
+		select {
+		case <-clock.After(delay):
+		case <-ctx.Done():
+			val, _ = f.Read()
+			return val, ctx.Err()
+		}
+		delay *= 2
+		if delay > max {
+			delay = max
+		}
+	}
+}
+
+// minPollDelay is the shortest sleep between polls in WaitPoll.
+const minPollDelay = time.Millisecond
+
+func maxDuration(a, b time.Duration) time.Duration {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// ReadDeadline waits for the Chan to close, but no later
+// than the absolute time t. It returns the closeVal and
+// isClosed status, with timedOut true if t passed
+// before the Chan closed. This is more convenient than
+// building a context.WithDeadline just to discard it.
+//
+// If the Chan is already closed, ReadDeadline returns
+// at once, even if t has passed. The deadline is
+// measured on the Chan's Clock.
+func (f *Chan[T]) ReadDeadline(t time.Time) (closeVal *T, isClosed bool, timedOut bool) {
+	f.waiting.Add(1)
+	defer f.waiting.Add(-1)
+	clock := f.getClock()
+	select {
+	case <-f.WhenClosed():
+	default:
+		select {
+		case <-f.WhenClosed():
+		case <-clock.After(t.Sub(clock.Now())):
+		}
+	}
+	closeVal, isClosed = f.Read()
+	timedOut = !isClosed
+	return
+}
+
+// ReadContext waits for the Chan to close, and then
+// returns the final closeVal with a nil error. If ctx
+// is done first, it returns the current closeVal with
+// ctx.Err(). Cancellation takes priority: if ctx is
+// already done on entry, ctx.Err() is returned
+// without looking at the Chan, even if it is closed.
+//
+// ReadContext sees the isClosed flag itself, so
+// it also returns for a Chan closed by MarkClosed.
+func (f *Chan[T]) ReadContext(ctx context.Context) (closeVal *T, err error) {
+	if err = ctx.Err(); err != nil {
+		closeVal, _ = f.Read()
+		return
+	}
+	return f.readContext(ctx)
+}
+
+// ReadBlockingIfOpen returns the closeVal at once, with
+// a nil error, if the Chan is already closed; otherwise
+// it waits for the close like ReadContext.
+//
+// This collapses the poll-versus-wait decision into
+// one call, which is what many callers want: "give me
+// the final value, waiting for it only if I must."
+// The subtlety is in the priorities. A closed Chan
+// wins over a done ctx here, whereas ReadContext
+// lets cancellation win. So a caller whose ctx has
+// expired still gets the final value if it is
+// there, and can only ever get ctx.Err() from
+// an open Chan.
+func (f *Chan[T]) ReadBlockingIfOpen(ctx context.Context) (closeVal *T, err error) {
+	return f.readContext(ctx)
+}
+
+// readContext waits for the Chan to close or ctx to
+// be done, checking the Chan first.
+func (f *Chan[T]) readContext(ctx context.Context) (closeVal *T, err error) {
+	f.waiting.Add(1)
+	defer f.waiting.Add(-1)
+	for {
+		val, isClosed, changed := f.readAndWatch()
+		if isClosed {
+			return val, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			closeVal, _ = f.Read()
+			return closeVal, ctx.Err()
+		}
+	}
+}
+
+// FirstClosed waits for the first of chans to close,
+// and returns its index and closeVal, with a nil error.
+// If ctx is done first, it returns idx -1, a nil val,
+// and ctx.Err(). This fits the hedged-request pattern,
+// where the same request is sent to several replicas,
+// each reporting on its own Chan, the first answer
+// wins, and the caller then cancels the rest.
+//
+// Chans already closed on entry are preferred, lowest
+// index first. FirstClosed waits in a single reflect.Select
+// over all of the WhenClosed channels, so no goroutines
+// are started, and nothing is left behind when it
+// returns. With no chans, it waits for ctx.
+func FirstClosed[T any](ctx context.Context, chans []*Chan[T]) (idx int, val *T, err error) {
+	for i, c := range chans {
+		if v, isClosed := c.Read(); isClosed {
+			return i, v, nil
+		}
+	}
+	cases := make([]reflect.SelectCase, len(chans)+1)
+	for i, c := range chans {
+		cases[i] = reflect.SelectCase{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(c.WhenClosed()),
+		}
+	}
+	cases[len(chans)] = reflect.SelectCase{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(ctx.Done()),
+	}
+	chosen, _, _ := reflect.Select(cases)
+	if chosen == len(chans) {
+		return -1, nil, ctx.Err()
+	}
+	val, _ = chans[chosen].Read()
+	return chosen, val, nil
+}
+
+// WaitSpin waits for the Chan to close, and returns the
+// final closeVal. It first polls the lock-free Closed up
+// to spins times, yielding the processor with
+// runtime.Gosched between polls, before falling back
+// to blocking on WhenClosed. For very-low-latency
+// handoffs, where the close usually follows within
+// microseconds, this avoids the cost of parking
+// and waking the waiting goroutine.
+//
+// Spinning burns CPU that other goroutines could use,
+// and gains nothing when the close is not imminent, so
+// keep spins small: tens, not thousands. WaitSpin(0)
+// is just a blocking wait. WaitSpin has no timeout;
+// see ReadContext for a cancellable wait.
+func (f *Chan[T]) WaitSpin(spins int) *T {
+	f.waiting.Add(1)
+	defer f.waiting.Add(-1)
+	for i := 0; i < spins; i++ {
+		if f.Closed() {
+			val, _ := f.Read()
+			return val
+		}
+		runtime.Gosched()
+	}
+	if !f.Closed() {
+		<-f.WhenClosed()
+	}
+	val, _ := f.Read()
+	return val
+}
+
+// Updates returns an iterator over the evolution of
+// the Chan, for use with range:
+//
+//	for val := range status.Updates(ctx) {
+//	    ... each new closeVal, the last being the final one ...
+//	}
+//
+// It yields each new closeVal as it is set, and then
+// the final closeVal when the Chan closes, after which
+// the iteration ends. It also ends when ctx is done,
+// or when the loop body breaks out. The closeVal
+// current at the start of the iteration is not
+// yielded, unless the Chan is already closed, in which
+// case only that final closeVal is yielded.
+//
+// Updates never blocks the setters. A loop body that is
+// slower than the updates sees only the latest closeVal
+// at each step, skipping those in between, though it
+// always sees the final one. Use SubscribeBuffered where
+// every single value matters more than the setters'
+// progress. While waiting for the next value, but
+// not while the loop body runs, the iteration
+// counts as a waiter for HasWaiters.
+func (f *Chan[T]) Updates(ctx context.Context) iter.Seq[*T] {
+	return func(yield func(*T) bool) {
+		s, changed := f.watchState()
+		if s.IsClosed {
+			yield(s.CloseVal)
+			return
+		}
+		last := s.Version
+		for {
+			f.waiting.Add(1)
+			select {
+			case <-changed:
+			case <-ctx.Done():
+				f.waiting.Add(-1)
+				return
+			}
+			f.waiting.Add(-1)
+			s, changed = f.watchState()
+			if s.Version == last && !s.IsClosed {
+				continue
+			}
+			last = s.Version
+			if !yield(s.CloseVal) || s.IsClosed {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,473 @@
+package loquet_test
+
+import (
+	"context"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+// Shape is a tagged union.
+type Shape struct {
+	Kind   string
+	Radius float64
+	Side   float64
+}
+
+func isCircle(s *Shape) bool {
+	return s != nil && s.Kind == "circle"
+}
+
+func TestWaitForVariantAppears(t *testing.T) {
+	c := loquet.NewChan(&Shape{Kind: "square", Side: 1})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.Set(&Shape{Kind: "square", Side: 2})
+		c.Set(&Shape{Kind: "circle", Radius: 3})
+	}()
+	val, isClosed, err := loquet.WaitForVariant(context.Background(), c, isCircle)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if isClosed || !isCircle(val) || val.Radius != 3 {
+		t.Fatalf("expected open circle of radius 3, got %#v, isClosed=%v", val, isClosed)
+	}
+}
+
+func TestWaitForVariantCloseFirst(t *testing.T) {
+	c := loquet.NewChan(&Shape{Kind: "square"})
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.CloseWith(&Shape{Kind: "triangle"})
+	}()
+	val, isClosed, err := loquet.WaitForVariant(context.Background(), c, isCircle)
+	if err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if !isClosed || val.Kind != "triangle" {
+		t.Fatalf("expected closed triangle, got %#v, isClosed=%v", val, isClosed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	open := loquet.NewChan(&Shape{Kind: "square"})
+	_, _, err = loquet.WaitForVariant(ctx, open, isCircle)
+	if err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestWaitVersions(t *testing.T) {
+	vals := []int{0, 1, 2, 3}
+	c := loquet.NewChan[int](&vals[0])
+	c.Set(&vals[0]) // before the call: not counted.
+
+	go func() {
+		for i := 1; i < len(vals); i++ {
+			time.Sleep(5 * time.Millisecond)
+			c.Set(&vals[i])
+		}
+	}()
+	val, err := c.WaitVersions(context.Background(), 3)
+	if err != nil || val != &vals[3] {
+		t.Fatalf("expected the third update, got %v, %v", val, err)
+	}
+
+	// a plain Close does not advance the version.
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.Close()
+	}()
+	_, err = c.WaitVersions(context.Background(), 1)
+	if err != loquet.ErrClosedBeforeMatch {
+		t.Fatalf("expected ErrClosedBeforeMatch, got %v", err)
+	}
+
+	o := loquet.NewChan[int](nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err = o.WaitVersions(ctx, 1); err != context.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestWaitVersionsWithSequence(t *testing.T) {
+	var seq loquet.Sequence
+	c := loquet.NewChan[int](nil, loquet.WithSequence[int](&seq))
+	other := loquet.NewChan[int](nil, loquet.WithSequence[int](&seq))
+
+	vals := []int{1, 2}
+	got := make(chan *int, 1)
+	go func() {
+		val, _ := c.WaitVersions(context.Background(), 2)
+		got <- val
+	}()
+	for !c.HasWaiters() {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond) // let it take its snapshot.
+	for i := 0; i < 5; i++ {
+		other.Set(nil) // the version of c will jump.
+	}
+	c.Set(&vals[0])
+	select {
+	case <-got:
+		t.Fatalf("expected a single Set not to count as two")
+	case <-time.After(20 * time.Millisecond):
+	}
+	c.Set(&vals[1])
+	if val := <-got; val != &vals[1] {
+		t.Fatalf("expected the second Set, got %v", val)
+	}
+}
+
+func TestWaitUntil(t *testing.T) {
+	zero := 0
+	c := loquet.NewChan(&zero)
+	atLeast := func(n int) func(*int) bool {
+		return func(v *int) bool { return v != nil && *v >= n }
+	}
+
+	go func() {
+		for i := 1; i <= 5; i++ {
+			v := i
+			c.Set(&v)
+		}
+	}()
+	val, err := c.WaitUntil(context.Background(), atLeast(3))
+	if err != nil || *val < 3 {
+		t.Fatalf("expected a value >= 3 and nil error, got %v, %v", *val, err)
+	}
+
+	go c.Close()
+	val, err = c.WaitUntil(context.Background(), atLeast(100))
+	if err != loquet.ErrClosedBeforeMatch {
+		t.Fatalf("expected ErrClosedBeforeMatch, got %v", err)
+	}
+	if val == nil {
+		t.Fatalf("expected the final closeVal along with the error")
+	}
+}
+
+func TestWaitWithEscalationSlowClose(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	warned := 0
+	t0 := time.Now()
+	_, isClosed, err := c.WaitWithEscalation(context.Background(),
+		10*time.Millisecond, func(*int) { warned++ },
+		50*time.Millisecond)
+
+	if err != context.DeadlineExceeded || isClosed {
+		t.Fatalf("expected DeadlineExceeded on an open Chan, got %v, %v", err, isClosed)
+	}
+	if warned != 1 {
+		t.Fatalf("expected onWarn exactly once, got %v", warned)
+	}
+	if elapsed := time.Since(t0); elapsed < 50*time.Millisecond {
+		t.Fatalf("returned before t2, after %v", elapsed)
+	}
+}
+
+func TestWaitWithEscalationFastClose(t *testing.T) {
+	one := 1
+	c := loquet.NewChan[int](nil)
+	c.CloseWith(&one)
+	val, isClosed, err := c.WaitWithEscalation(context.Background(),
+		time.Hour, func(*int) { t.Fatalf("onWarn should be skipped") },
+		2*time.Hour)
+	if err != nil || !isClosed || *val != 1 {
+		t.Fatalf("expected closed with 1 and nil error, got %v, %v, %v", val, isClosed, err)
+	}
+}
+
+func TestCloseWithAndWait(t *testing.T) {
+	msg := &Message{}
+	c := loquet.NewChan[Message](nil)
+
+	got := make(chan *Message)
+	go func() {
+		<-c.WhenClosed()
+		time.Sleep(10 * time.Millisecond)
+		val, _ := c.Read()
+		got <- val
+	}()
+	if err := c.CloseWithAndWait(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if val := <-got; val != msg {
+		t.Fatalf("consumer should have read the closeVal")
+	}
+
+	if err := c.CloseWithAndWait(context.Background(), msg); err != loquet.ErrAlreadyClosed {
+		t.Fatalf("expected ErrAlreadyClosed, got %v", err)
+	}
+}
+
+func TestCloseWithAndWaitNoReader(t *testing.T) {
+	c := loquet.NewChan[Message](nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := c.CloseWithAndWait(ctx, &Message{}); err != context.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded with no reader, got %v", err)
+	}
+	if _, isClosed := c.Read(); !isClosed {
+		t.Fatalf("Chan should remain closed")
+	}
+}
+
+func TestCloseWithAndWaitSubscriber(t *testing.T) {
+	c := loquet.NewChan[Message](nil)
+	_, cancel := c.Subscribe()
+	defer cancel()
+	// delivery to the subscriber's buffer counts as observed.
+	if err := c.CloseWithAndWait(context.Background(), &Message{}); err != nil {
+		t.Fatalf("unexpected error %v", err)
+	}
+}
+
+func TestWaitPoll(t *testing.T) {
+	msg := &Message{}
+	c := loquet.NewChan[Message](nil)
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.CloseWith(msg)
+	}()
+	val, err := c.WaitPoll(context.Background(), time.Millisecond, 4*time.Millisecond)
+	if err != nil || val != msg {
+		t.Fatalf("expected the closeVal and nil error, got %v, %v", val, err)
+	}
+
+	open := loquet.NewChan[Message](nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := open.WaitPoll(ctx, time.Millisecond, time.Millisecond); err != context.DeadlineExceeded {
+		t.Fatalf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+// pollClock records the sleeps WaitPoll asks for,
+// without sleeping, and closes c after polls of them.
+type pollClock struct {
+	c      *loquet.Chan[int]
+	polls  int
+	delays []time.Duration
+}
+
+func (p *pollClock) Now() time.Time { return time.Now() }
+
+func (p *pollClock) After(d time.Duration) <-chan time.Time {
+	p.delays = append(p.delays, d)
+	if len(p.delays) == p.polls {
+		p.c.Close()
+	}
+	ch := make(chan time.Time, 1)
+	ch <- time.Now()
+	return ch
+}
+
+func pollDelays(initial, max time.Duration) []time.Duration {
+	clock := &pollClock{polls: 6}
+	clock.c = loquet.NewChan[int](nil, loquet.WithClock[int](clock))
+	clock.c.WaitPoll(context.Background(), initial, max)
+	return clock.delays
+}
+
+func TestWaitPollNeverSpins(t *testing.T) {
+	for _, d := range pollDelays(0, 0) {
+		if d < time.Millisecond {
+			t.Fatalf("expected a zero initial raised to the minimum, got %v", d)
+		}
+	}
+	for _, d := range pollDelays(10*time.Millisecond, time.Millisecond) {
+		if d != 10*time.Millisecond {
+			t.Fatalf("expected a max below initial to hold the delay at initial, got %v", d)
+		}
+	}
+	delays := pollDelays(time.Millisecond, 4*time.Millisecond)
+	if delays[0] != time.Millisecond || delays[len(delays)-1] != 4*time.Millisecond {
+		t.Fatalf("expected backoff from 1ms up to 4ms, got %v", delays)
+	}
+}
+
+func TestReadDeadline(t *testing.T) {
+	c := loquet.NewChan[Message](nil)
+	_, isClosed, timedOut := c.ReadDeadline(time.Now().Add(10 * time.Millisecond))
+	if isClosed || !timedOut {
+		t.Fatalf("expected a timeout on an open Chan")
+	}
+
+	msg := &Message{}
+	go func() {
+		time.Sleep(5 * time.Millisecond)
+		c.CloseWith(msg)
+	}()
+	val, isClosed, timedOut := c.ReadDeadline(time.Now().Add(5 * time.Second))
+	if !isClosed || timedOut || val != msg {
+		t.Fatalf("expected the closeVal before the deadline")
+	}
+
+	// already closed: returns even with a past deadline.
+	if _, _, timedOut = c.ReadDeadline(time.Now().Add(-time.Hour)); timedOut {
+		t.Fatalf("a closed Chan should not time out")
+	}
+}
+
+func TestReadContext(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	one := 1
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.CloseWith(&one)
+	}()
+	val, err := c.ReadContext(context.Background())
+	if err != nil || val != &one {
+		t.Fatalf("expected 1 and nil error, got %v, %v", val, err)
+	}
+
+	// cancellation wins in ReadContext, even when closed.
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.ReadContext(done); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestReadBlockingIfOpen(t *testing.T) {
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// closed wins over a done ctx.
+	one := 1
+	c := loquet.NewChan[int](&one)
+	c.Close()
+	if val, err := c.ReadBlockingIfOpen(done); err != nil || val != &one {
+		t.Fatalf("expected 1 with nil error, got %v, %v", val, err)
+	}
+
+	open := loquet.NewChan[int](&one)
+	if val, err := open.ReadBlockingIfOpen(done); err != context.Canceled || val != &one {
+		t.Fatalf("expected the current value with context.Canceled, got %v, %v", val, err)
+	}
+
+	ctx, cancel2 := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel2()
+	go open.Close()
+	if _, err := open.ReadBlockingIfOpen(ctx); err != nil {
+		t.Fatalf("expected to wake on close, got %v", err)
+	}
+}
+
+func TestFirstClosed(t *testing.T) {
+	chans := loquet.NewChans[int](3, nil)
+	two := 2
+	go chans[2].CloseWith(&two)
+	idx, val, err := loquet.FirstClosed(context.Background(), chans)
+	if err != nil || idx != 2 || *val != 2 {
+		t.Fatalf("expected replica 2 to win, got %v, %v, %v", idx, val, err)
+	}
+
+	// already closed: the lowest index wins.
+	one := 1
+	chans[1].CloseWith(&one)
+	if idx, _, _ := loquet.FirstClosed(context.Background(), chans); idx != 1 {
+		t.Fatalf("expected index 1, got %v", idx)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	open := loquet.NewChans[int](2, nil)
+	if idx, val, err := loquet.FirstClosed(ctx, open); idx != -1 || val != nil || err != context.DeadlineExceeded {
+		t.Fatalf("expected -1, nil, DeadlineExceeded; got %v, %v, %v", idx, val, err)
+	}
+}
+
+func TestWaitSpin(t *testing.T) {
+	one := 1
+	c := loquet.NewChan[int](nil)
+	go c.CloseWith(&one)
+	if v := c.WaitSpin(10); v != &one {
+		t.Fatalf("expected the closeVal")
+	}
+	if v := c.WaitSpin(0); v != &one {
+		t.Fatalf("expected the closeVal from a closed Chan")
+	}
+}
+
+// benchmarkHandoff measures the round trip of a close
+// made by another goroutine as soon as it can.
+func benchmarkHandoff(b *testing.B, spins int) {
+	withoutDebugChecks(b)
+	for i := 0; i < b.N; i++ {
+		c := loquet.NewChan[int](nil)
+		go c.Close()
+		c.WaitSpin(spins)
+	}
+}
+
+func BenchmarkHandoffBlock(b *testing.B) { benchmarkHandoff(b, 0) }
+func BenchmarkHandoffSpin(b *testing.B)  { benchmarkHandoff(b, 20) }
+
+func TestUpdatesCountsAsWaiter(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range c.Updates(context.Background()) {
+		}
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for !c.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a blocked Updates loop to count as a waiter")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Close()
+	<-done
+	if c.HasWaiters() {
+		t.Fatalf("expected no waiters once the loop ended")
+	}
+}
+
+func TestUpdates(t *testing.T) {
+	c := loquet.NewChan[int](nil)
+	step := make(chan struct{})
+	go func() {
+		time.Sleep(20 * time.Millisecond) // let the range start.
+		for i := 1; i <= 3; i++ {
+			c.Set(&i)
+			<-step // let the loop body see each value.
+		}
+		c.Close()
+	}()
+
+	var got []int
+	for v := range c.Updates(context.Background()) {
+		got = append(got, *v)
+		if len(got) <= 3 {
+			step <- struct{}{}
+		}
+	}
+	if !slices.Equal(got, []int{1, 2, 3, 3}) {
+		t.Fatalf("expected 1,2,3 then the final 3; got %v", got)
+	}
+
+	// already closed: just the final value.
+	got = nil
+	for v := range c.Updates(context.Background()) {
+		got = append(got, *v)
+	}
+	if !slices.Equal(got, []int{3}) {
+		t.Fatalf("expected only the final 3, got %v", got)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range loquet.NewChan[int](nil).Updates(ctx) {
+		t.Fatalf("expected no values once ctx is done")
+	}
+}
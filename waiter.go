@@ -0,0 +1,75 @@
+package loquet
+
+import (
+	"context"
+	"reflect"
+)
+
+// Waiter is anything that can be waited on to close,
+// as a *Chan[T] can, whatever its T. Functions such as
+// SelectAny take Waiters, so that Chans of different
+// types can be coordinated together, and so that
+// user-written helpers can accept any Chan,
+// or a stand-in for one in tests.
+type Waiter interface {
+	// WhenClosed returns a channel that is
+	// closed when the Waiter closes.
+	WhenClosed() <-chan struct{}
+}
+
+// *Chan[T] is a Waiter for every T.
+var _ Waiter = (*Chan[struct{}])(nil)
+
+// SelectAny blocks until any one of chans closes, and
+// returns its index. Unlike FirstClosed, the chans
+// may be Chans of different types, as in
+//
+//	idx := loquet.SelectAny(jobDone, configChanged, shutdown)
+//
+// which suits mixed-type shutdown coordination. The
+// closeVal is then read from chans[idx] by the caller,
+// who knows its type.
+//
+// SelectAny is WaitAny without a way to give up
+// early. With no chans, it blocks forever, like
+// an empty select statement.
+func SelectAny(chans ...Waiter) (idx int) {
+	idx, _ = WaitAny(context.Background(), chans...)
+	return
+}
+
+// WaitAny waits until any one of chans closes, and
+// returns its index with a nil error. If ctx is done
+// first, it returns idx -1 and ctx.Err(). Like
+// SelectAny, it accepts Chans of mixed types, and
+// any other Waiter, such as a mock in a test.
+//
+// Waiters already closed on entry are preferred,
+// lowest index first. Otherwise WaitAny waits in a
+// single reflect.Select, starting no goroutines, so
+// nothing is left behind when it returns. With no
+// chans, it waits for ctx.
+func WaitAny(ctx context.Context, chans ...Waiter) (idx int, err error) {
+	cases := make([]reflect.SelectCase, len(chans)+1)
+	for i, c := range chans {
+		ch := c.WhenClosed()
+		select {
+		case <-ch:
+			return i, nil
+		default:
+		}
+		cases[i] = reflect.SelectCase{
+			Dir:  reflect.SelectRecv,
+			Chan: reflect.ValueOf(ch),
+		}
+	}
+	cases[len(chans)] = reflect.SelectCase{
+		Dir:  reflect.SelectRecv,
+		Chan: reflect.ValueOf(ctx.Done()),
+	}
+	idx, _, _ = reflect.Select(cases)
+	if idx == len(chans) {
+		return -1, ctx.Err()
+	}
+	return
+}
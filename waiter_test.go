@@ -0,0 +1,56 @@
+package loquet_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/glycerine/loquet"
+)
+
+func TestSelectAny(t *testing.T) {
+	a := loquet.NewChan[int](nil)
+	b := loquet.NewChan[string](nil)
+	c := loquet.NewChan[Message](nil)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		b.Close()
+	}()
+	if idx := loquet.SelectAny(a, b, c); idx != 1 {
+		t.Fatalf("expected the string Chan at index 1 to win, got %v", idx)
+	}
+
+	// already closed: lowest index first.
+	c.Close()
+	if idx := loquet.SelectAny(a, c, b); idx != 1 {
+		t.Fatalf("expected the lowest closed index 1, got %v", idx)
+	}
+}
+
+// mockWaiter stands in for a Chan, as users
+// may do in their own tests.
+type mockWaiter chan struct{}
+
+func (w mockWaiter) WhenClosed() <-chan struct{} { return w }
+
+func TestWaitAny(t *testing.T) {
+	a := loquet.NewChan[int](nil)
+	fake := make(mockWaiter)
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		close(fake)
+	}()
+	idx, err := loquet.WaitAny(context.Background(), a, fake)
+	if idx != 1 || err != nil {
+		t.Fatalf("expected the fake at index 1, got %v, %v", idx, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	idx, err = loquet.WaitAny(ctx, a, loquet.NewChan[string](nil))
+	if idx != -1 || err != context.DeadlineExceeded {
+		t.Fatalf("expected -1 and DeadlineExceeded, got %v, %v", idx, err)
+	}
+}